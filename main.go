@@ -1,12 +1,19 @@
 package main
 
 import (
+	"os"
+	"rooster-importer/pkg/calendar"
+	"rooster-importer/pkg/cli"
 	"rooster-importer/pkg/domain"
 	"rooster-importer/pkg/ui"
 )
 
 func main() {
-	application := domain.NewApplication()
+	if args, ok := cli.CommandArgs(os.Args[1:]); ok {
+		os.Exit(cli.Run(args))
+	}
+
+	application := domain.NewApplication(calendar.GoogleProvider{})
 
 	gui := ui.CreateAppUI()
 
@@ -0,0 +1,107 @@
+package calendar
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"time"
+)
+
+// Backend is a calendar service in which events can be listed, created, updated and deleted.
+type Backend interface {
+	ListCalendars(ctx context.Context) ([]CalendarItem, error)
+	// ListEvents lists the events between from and to. A zero from or to leaves that side of the range open. Recurring
+	// events are expanded into their occurrences when the range is closed, an open range would make them repeat
+	// forever. Events that cannot be read are skipped and reported with a *SkippedEventsError, which is returned
+	// together with the other events.
+	ListEvents(ctx context.Context, calendarId string, from, to time.Time) ([]CalendarEvent, error)
+	// CreateEvent creates an event, and returns it with the id the backend knows it by. When the Id of the event is set
+	// to an id made by NewEventId, the event is created under that id, and creating it again doesn't make a second
+	// event: the id is new, so an event that already exists under it was created by an earlier attempt of which the
+	// response got lost, and is returned instead. That makes it safe to retry a create that may have succeeded.
+	CreateEvent(ctx context.Context, calendarId string, event *CalendarEvent) (*CalendarEvent, error)
+	UpdateEvent(ctx context.Context, calendarId string, event *CalendarEvent) (*CalendarEvent, error)
+	DeleteEvent(ctx context.Context, calendarId string, eventId string) error
+}
+
+// NewEventId makes a random id for a new event, which is accepted by both Google Calendar (lowercase base32hex) and
+// CalDAV servers (as the UID and name of the event).
+func NewEventId() (string, error) {
+	b := make([]byte, 16)
+
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+
+	return hex.EncodeToString(b), nil
+}
+
+// SkippedEventsError reports the events that were skipped when listing events, because they could not be read.
+type SkippedEventsError struct {
+	Errors []error
+}
+
+func (e *SkippedEventsError) Error() string {
+	return fmt.Sprintf("%d events in the calendar could not be read and were skipped, 1st error: %s", len(e.Errors), e.Errors[0])
+}
+
+// Provider keeps track of the login state of a calendar backend.
+type Provider interface {
+	Name() string
+	LogIn() (Backend, error)
+	LogOut() error
+	IsLoggedIn() bool
+}
+
+// GoogleProvider logs in to Google Calendar using OAuth2, and stores the token in the user's cache directory.
+type GoogleProvider struct{}
+
+func (GoogleProvider) Name() string {
+	return "Google Calendar"
+}
+
+func (GoogleProvider) LogIn() (Backend, error) {
+	client, err := LogIn()
+
+	if err != nil {
+		return nil, err
+	}
+
+	return WithRetry(client, DefaultRetry), nil
+}
+
+func (GoogleProvider) LogOut() error {
+	return LogOut()
+}
+
+func (GoogleProvider) IsLoggedIn() bool {
+	return IsLoggedIn()
+}
+
+// FindCalendarIdByName finds the ID of the calendar with the given name, which must be unique.
+func FindCalendarIdByName(ctx context.Context, backend Backend, calendarName string) (string, error) {
+	calendars, err := backend.ListCalendars(ctx)
+
+	if err != nil {
+		return "", err
+	}
+
+	id := ""
+	occurrences := 0
+
+	for _, item := range calendars {
+		if calendarName == item.Name {
+			id = item.Id
+			occurrences += 1
+		}
+	}
+
+	if occurrences == 1 {
+		return id, nil
+	} else if occurrences == 0 {
+		return "", fmt.Errorf("calendar name %s not found", calendarName)
+	} else {
+		return "", fmt.Errorf("calendar name %s is not unique (%d occurrences)", calendarName, occurrences)
+	}
+}
@@ -0,0 +1,613 @@
+package calendar
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"os"
+	"rooster-importer/pkg/config"
+	"rooster-importer/pkg/ics"
+	"strings"
+	"time"
+)
+
+// CalDAVCredentials are the details needed to log into a CalDAV server. Most providers (Nextcloud, Fastmail, iCloud)
+// require an app password rather than the password of the account. The password is left out when the credentials
+// are stored.
+type CalDAVCredentials struct {
+	URL      string `json:"url"`
+	Username string `json:"username"`
+	Password string `json:"-"`
+}
+
+// CalDAVClient talks to a CalDAV server (RFC 4791). Calendar and event IDs are the URLs of the resources on the server.
+type CalDAVClient struct {
+	client      *http.Client
+	base        *url.URL
+	credentials CalDAVCredentials
+}
+
+var ErrCalDAVUnauthorized = errors.New("the CalDAV server did not accept the username or password")
+
+func NewCalDAVClient(credentials CalDAVCredentials, client *http.Client) (*CalDAVClient, error) {
+	base, err := url.Parse(credentials.URL)
+
+	if err != nil {
+		return nil, fmt.Errorf("invalid CalDAV URL: %w", err)
+	}
+
+	if base.Scheme != "http" && base.Scheme != "https" {
+		return nil, fmt.Errorf("invalid CalDAV URL %s: should start with https://", credentials.URL)
+	}
+
+	if client == nil {
+		client = http.DefaultClient
+	}
+
+	return &CalDAVClient{
+		client:      client,
+		base:        base,
+		credentials: credentials,
+	}, nil
+}
+
+type davMultistatus struct {
+	XMLName   xml.Name      `xml:"DAV: multistatus"`
+	Responses []davResponse `xml:"DAV: response"`
+}
+
+type davResponse struct {
+	Href      string        `xml:"DAV: href"`
+	Propstats []davPropstat `xml:"DAV: propstat"`
+}
+
+type davPropstat struct {
+	Prop   davProp `xml:"DAV: prop"`
+	Status string  `xml:"DAV: status"`
+}
+
+type davProp struct {
+	CurrentUserPrincipal *davHref        `xml:"DAV: current-user-principal"`
+	CalendarHomeSet      *davHref        `xml:"urn:ietf:params:xml:ns:caldav calendar-home-set"`
+	DisplayName          string          `xml:"DAV: displayname"`
+	ResourceType         davResourceType `xml:"DAV: resourcetype"`
+	CalendarColor        string          `xml:"http://apple.com/ns/ical/ calendar-color"`
+	CalendarData         string          `xml:"urn:ietf:params:xml:ns:caldav calendar-data"`
+}
+
+type davHref struct {
+	Href string `xml:"DAV: href"`
+}
+
+type davResourceType struct {
+	Calendar *struct{} `xml:"urn:ietf:params:xml:ns:caldav calendar"`
+}
+
+// props returns the properties of a response that were found.
+func (r *davResponse) props() []davProp {
+	props := []davProp{}
+
+	for _, propstat := range r.Propstats {
+		if propstat.Status == "" || strings.Contains(propstat.Status, " 200 ") {
+			props = append(props, propstat.Prop)
+		}
+	}
+
+	return props
+}
+
+func (c *CalDAVClient) resolve(href string) (*url.URL, error) {
+	ref, err := url.Parse(href)
+
+	if err != nil {
+		return nil, fmt.Errorf("invalid href %s: %w", href, err)
+	}
+
+	return c.base.ResolveReference(ref), nil
+}
+
+func (c *CalDAVClient) do(ctx context.Context, method string, target string, headers map[string]string, body []byte) (*http.Response, error) {
+	u, err := c.resolve(target)
+
+	if err != nil {
+		return nil, err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, method, u.String(), bytes.NewReader(body))
+
+	if err != nil {
+		return nil, err
+	}
+
+	req.SetBasicAuth(c.credentials.Username, c.credentials.Password)
+
+	for key, value := range headers {
+		req.Header.Set(key, value)
+	}
+
+	res, err := c.client.Do(req)
+
+	if err != nil {
+		return nil, err
+	}
+
+	if res.StatusCode == http.StatusUnauthorized || res.StatusCode == http.StatusForbidden {
+		res.Body.Close()
+		return nil, ErrCalDAVUnauthorized
+	}
+
+	if res.StatusCode >= 300 {
+		res.Body.Close()
+		return nil, &StatusError{
+			Method:     method,
+			Path:       u.Path,
+			Status:     res.Status,
+			StatusCode: res.StatusCode,
+			RetryAfter: res.Header.Get("Retry-After"),
+		}
+	}
+
+	return res, nil
+}
+
+func (c *CalDAVClient) multistatus(ctx context.Context, method, target, depth, body string) (*davMultistatus, error) {
+	headers := map[string]string{
+		"Depth":        depth,
+		"Content-Type": "application/xml; charset=utf-8",
+	}
+
+	res, err := c.do(ctx, method, target, headers, []byte(body))
+
+	if err != nil {
+		return nil, err
+	}
+	defer res.Body.Close()
+
+	ms := &davMultistatus{}
+
+	if err := xml.NewDecoder(res.Body).Decode(ms); err != nil {
+		return nil, fmt.Errorf("cannot decode %s response: %w", method, err)
+	}
+
+	return ms, nil
+}
+
+// findHref asks for a single property that contains a href, like the principal of the user.
+func (c *CalDAVClient) findHref(ctx context.Context, target string, body string, get func(*davProp) *davHref) (string, error) {
+	ms, err := c.multistatus(ctx, "PROPFIND", target, "0", body)
+
+	if err != nil {
+		return "", err
+	}
+
+	for _, response := range ms.Responses {
+		for _, prop := range response.props() {
+			if href := get(&prop); href != nil && href.Href != "" {
+				return href.Href, nil
+			}
+		}
+	}
+
+	return "", nil
+}
+
+// calendarHome finds the collection that contains the calendars of the user. Servers that don't support discovery
+// are expected to be given the URL of the calendar home directly.
+func (c *CalDAVClient) calendarHome(ctx context.Context) (string, error) {
+	principal, err := c.findHref(ctx, c.base.String(), `<?xml version="1.0" encoding="utf-8"?>
+<d:propfind xmlns:d="DAV:"><d:prop><d:current-user-principal/></d:prop></d:propfind>`,
+		func(p *davProp) *davHref { return p.CurrentUserPrincipal })
+
+	if err != nil {
+		return "", fmt.Errorf("cannot find principal: %w", err)
+	}
+
+	if principal == "" {
+		principal = c.base.String()
+	}
+
+	home, err := c.findHref(ctx, principal, `<?xml version="1.0" encoding="utf-8"?>
+<d:propfind xmlns:d="DAV:" xmlns:c="urn:ietf:params:xml:ns:caldav"><d:prop><c:calendar-home-set/></d:prop></d:propfind>`,
+		func(p *davProp) *davHref { return p.CalendarHomeSet })
+
+	if err != nil {
+		return "", fmt.Errorf("cannot find calendar home: %w", err)
+	}
+
+	if home == "" {
+		home = principal
+	}
+
+	return home, nil
+}
+
+func (c *CalDAVClient) ListCalendars(ctx context.Context) ([]CalendarItem, error) {
+	home, err := c.calendarHome(ctx)
+
+	if err != nil {
+		return nil, err
+	}
+
+	ms, err := c.multistatus(ctx, "PROPFIND", home, "1", `<?xml version="1.0" encoding="utf-8"?>
+<d:propfind xmlns:d="DAV:" xmlns:a="http://apple.com/ns/ical/"><d:prop><d:resourcetype/><d:displayname/><a:calendar-color/></d:prop></d:propfind>`)
+
+	if err != nil {
+		return nil, fmt.Errorf("couldnt list calendars: %w", err)
+	}
+
+	items := []CalendarItem{}
+
+	for _, response := range ms.Responses {
+		for _, prop := range response.props() {
+			if prop.ResourceType.Calendar == nil {
+				continue
+			}
+
+			u, err := c.resolve(response.Href)
+
+			if err != nil {
+				return nil, err
+			}
+
+			name := prop.DisplayName
+
+			if name == "" {
+				name = strings.Trim(u.Path, "/")
+			}
+
+			items = append(items, CalendarItem{
+				Id:    u.String(),
+				Name:  name,
+				Color: prop.CalendarColor,
+			})
+		}
+	}
+
+	return items, nil
+}
+
+const caldavTimeFormat = "20060102T150405Z"
+
+func (c *CalDAVClient) ListEvents(ctx context.Context, calendarId string, from, to time.Time) ([]CalendarEvent, error) {
+	timerange := ""
+	calendardata := "<c:calendar-data/>"
+
+	// the server only expands recurring events when asked for a range
+	if !from.IsZero() && !to.IsZero() {
+		calendardata = fmt.Sprintf(`<c:calendar-data><c:expand start="%s" end="%s"/></c:calendar-data>`,
+			from.UTC().Format(caldavTimeFormat), to.UTC().Format(caldavTimeFormat))
+	}
+
+	if !from.IsZero() || !to.IsZero() {
+		timerange = "<c:time-range"
+
+		if !from.IsZero() {
+			timerange += fmt.Sprintf(` start="%s"`, from.UTC().Format(caldavTimeFormat))
+		}
+
+		if !to.IsZero() {
+			timerange += fmt.Sprintf(` end="%s"`, to.UTC().Format(caldavTimeFormat))
+		}
+
+		timerange += "/>"
+	}
+
+	query := fmt.Sprintf(`<?xml version="1.0" encoding="utf-8"?>
+<c:calendar-query xmlns:d="DAV:" xmlns:c="urn:ietf:params:xml:ns:caldav">
+  <d:prop><d:getetag/>%s</d:prop>
+  <c:filter><c:comp-filter name="VCALENDAR"><c:comp-filter name="VEVENT">%s</c:comp-filter></c:comp-filter></c:filter>
+</c:calendar-query>`, calendardata, timerange)
+
+	ms, err := c.multistatus(ctx, "REPORT", calendarId, "1", query)
+
+	if err != nil {
+		return nil, fmt.Errorf("couldn't get calendar events: %w", err)
+	}
+
+	events := []CalendarEvent{}
+	skipped := &SkippedEventsError{}
+
+	for _, response := range ms.Responses {
+		for _, prop := range response.props() {
+			if prop.CalendarData == "" {
+				continue
+			}
+
+			u, err := c.resolve(response.Href)
+
+			if err != nil {
+				return nil, err
+			}
+
+			parsed, err := ics.Parse(strings.NewReader(prop.CalendarData))
+
+			if err != nil {
+				skipped.Errors = append(skipped.Errors, fmt.Errorf("cannot read event %s: %w", u.Path, err))
+				continue
+			}
+
+			for _, event := range parsed {
+				events = append(events, CalendarEvent{
+					Id:       u.String(),
+					Title:    event.Title,
+					Start:    event.Start,
+					End:      event.End,
+					AllDay:   event.AllDay,
+					Identity: identityFromProperties(event.Properties),
+				})
+			}
+		}
+	}
+
+	if len(skipped.Errors) > 0 {
+		return events, skipped
+	}
+
+	return events, nil
+}
+
+func (c *CalDAVClient) putEvent(ctx context.Context, target string, uid string, event *CalendarEvent, headers map[string]string) error {
+	body := &bytes.Buffer{}
+
+	err := ics.Write(body, []ics.Event{{
+		UID:        uid,
+		Title:      event.Title,
+		Start:      event.Start,
+		End:        event.End,
+		AllDay:     event.AllDay,
+		Properties: event.Identity.properties(),
+	}})
+
+	if err != nil {
+		return err
+	}
+
+	headers["Content-Type"] = "text/calendar; charset=utf-8"
+
+	res, err := c.do(ctx, http.MethodPut, target, headers, body.Bytes())
+
+	if err != nil {
+		return err
+	}
+
+	return res.Body.Close()
+}
+
+// CreateEvent creates the event with the Id of the event as its UID, or a new UID when the Id is empty.
+func (c *CalDAVClient) CreateEvent(ctx context.Context, calendarId string, event *CalendarEvent) (*CalendarEvent, error) {
+	uid := event.Id
+
+	if uid == "" {
+		var err error
+
+		if uid, err = NewEventId(); err != nil {
+			return nil, err
+		}
+	}
+
+	target, err := url.JoinPath(calendarId, uid+".ics")
+
+	if err != nil {
+		return nil, err
+	}
+
+	// never overwrite an existing event when creating one, the server refuses a UID that is taken with a failed
+	// precondition, see Backend.CreateEvent
+	err = c.putEvent(ctx, target, uid, event, map[string]string{"If-None-Match": "*"})
+
+	var statusErr *StatusError
+
+	if err != nil && !(event.Id != "" && errors.As(err, &statusErr) && statusErr.StatusCode == http.StatusPreconditionFailed) {
+		return nil, fmt.Errorf("failed to create event: %w", err)
+	}
+
+	created := *event
+	created.Id = target
+
+	return &created, nil
+}
+
+// uidOf fetches an existing event, so that its UID is kept when it is updated.
+func (c *CalDAVClient) uidOf(ctx context.Context, eventId string) (string, error) {
+	res, err := c.do(ctx, http.MethodGet, eventId, nil, nil)
+
+	if err != nil {
+		return "", err
+	}
+	defer res.Body.Close()
+
+	events, err := ics.Parse(res.Body)
+
+	if err != nil {
+		return "", err
+	}
+
+	if len(events) == 0 || events[0].UID == "" {
+		return "", fmt.Errorf("%s does not contain an event", eventId)
+	}
+
+	return events[0].UID, nil
+}
+
+func (c *CalDAVClient) UpdateEvent(ctx context.Context, calendarId string, event *CalendarEvent) (*CalendarEvent, error) {
+	uid, err := c.uidOf(ctx, event.Id)
+
+	if err != nil {
+		return nil, fmt.Errorf("failed to update event: %w", err)
+	}
+
+	if err := c.putEvent(ctx, event.Id, uid, event, map[string]string{}); err != nil {
+		return nil, fmt.Errorf("failed to update event: %w", err)
+	}
+
+	updated := *event
+
+	return &updated, nil
+}
+
+func (c *CalDAVClient) DeleteEvent(ctx context.Context, calendarId string, eventId string) error {
+	res, err := c.do(ctx, http.MethodDelete, eventId, nil, nil)
+
+	if err != nil {
+		return fmt.Errorf("failed to delete event: %w", err)
+	}
+
+	return res.Body.Close()
+}
+
+// CalDAVPasswordEnv is the environment variable that holds the CalDAV password, for when it isn't given on login.
+const CalDAVPasswordEnv = "ROOSTER_CALDAV_PASSWORD"
+
+// caldavFile is where the URL and username of the last login are stored, in the config directory. Older versions
+// stored the whole login, password included, in a file with the same name in the cache directory.
+const caldavFile = "caldav.json"
+
+// CalDAVProvider logs into a CalDAV server. When Credentials are given, they are checked on login, after which the URL
+// and username are stored in the user's config directory. The password is never stored: it is remembered for as long as
+// the provider is used, and otherwise read from $ROOSTER_CALDAV_PASSWORD.
+type CalDAVProvider struct {
+	Credentials *CalDAVCredentials
+	password    string
+}
+
+func (p *CalDAVProvider) Name() string {
+	return "CalDAV"
+}
+
+func (p *CalDAVProvider) LogIn() (Backend, error) {
+	if err := removeCachedLogin(); err != nil {
+		return nil, err
+	}
+
+	credentials := p.Credentials
+
+	if credentials == nil {
+		credentials = &CalDAVCredentials{}
+
+		err := config.Load(caldavFile, credentials)
+
+		if config.IsNotExist(err) {
+			return nil, errors.New("no CalDAV server configured, log in with a URL, username and password first")
+		}
+
+		if err != nil {
+			return nil, fmt.Errorf("cannot read stored CalDAV login: %w", err)
+		}
+
+		credentials.Password = p.storedPassword()
+
+		if credentials.Password == "" {
+			return nil, fmt.Errorf("no CalDAV password for %s, log in again or set $%s", credentials.Username, CalDAVPasswordEnv)
+		}
+	}
+
+	client, err := NewCalDAVClient(*credentials, nil)
+
+	if err != nil {
+		return nil, err
+	}
+
+	if p.Credentials != nil {
+		// check the credentials before saving them
+		if _, err := client.calendarHome(context.Background()); err != nil {
+			return nil, err
+		}
+
+		if err := config.Save(caldavFile, credentials); err != nil {
+			return nil, fmt.Errorf("cannot save CalDAV login: %w", err)
+		}
+
+		p.password = credentials.Password
+		p.Credentials = nil
+	}
+
+	return WithRetry(client, DefaultRetry), nil
+}
+
+// storedPassword is the password of the last login, or else the password in the environment.
+func (p *CalDAVProvider) storedPassword() string {
+	if p.password != "" {
+		return p.password
+	}
+
+	return os.Getenv(CalDAVPasswordEnv)
+}
+
+// removeCachedLogin removes the login that older versions stored in the cache directory, which includes the password.
+// Its URL and username are kept in the config directory, unless a login is stored there already.
+func removeCachedLogin() error {
+	location, err := CacheLocation(caldavFile)
+
+	if err != nil {
+		return err
+	}
+
+	contents, err := os.ReadFile(location)
+
+	if errors.Is(err, os.ErrNotExist) {
+		return nil
+	}
+
+	if err != nil {
+		return err
+	}
+
+	credentials := CalDAVCredentials{}
+
+	if err := config.Load(caldavFile, &CalDAVCredentials{}); config.IsNotExist(err) && json.Unmarshal(contents, &credentials) == nil {
+		if err := config.Save(caldavFile, credentials); err != nil {
+			return fmt.Errorf("cannot save CalDAV login: %w", err)
+		}
+	}
+
+	return os.Remove(location)
+}
+
+func (p *CalDAVProvider) LogOut() error {
+	p.password = ""
+
+	if err := removeCachedLogin(); err != nil {
+		return err
+	}
+
+	location, err := config.Path(caldavFile)
+
+	if err != nil {
+		return err
+	}
+
+	err = os.Remove(location)
+
+	if errors.Is(err, os.ErrNotExist) {
+		return nil
+	}
+
+	return err
+}
+
+func (p *CalDAVProvider) IsLoggedIn() bool {
+	if p.Credentials != nil || p.storedPassword() == "" {
+		return false
+	}
+
+	location, err := config.Path(caldavFile)
+
+	if err != nil {
+		return false
+	}
+
+	if _, err := os.Stat(location); err == nil {
+		return true
+	}
+
+	// a login of an older version is moved on the next login
+	location, _ = CacheLocation(caldavFile)
+	_, err = os.Stat(location)
+
+	return err == nil
+}
@@ -0,0 +1,397 @@
+package calendar_test
+
+import (
+	"context"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"path/filepath"
+	"regexp"
+	"rooster-importer/pkg/calendar"
+	"rooster-importer/pkg/ics"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// fakeCalDAV is a minimal CalDAV server that supports discovery of calendars, and storing, querying and deleting events.
+type fakeCalDAV struct {
+	mu        sync.Mutex
+	calendars map[string]string
+	events    map[string]string
+}
+
+func newFakeCalDAV() *fakeCalDAV {
+	return &fakeCalDAV{
+		calendars: map[string]string{
+			"/dav/calendars/jan/werk/":  "Werk",
+			"/dav/calendars/jan/prive/": "Prive",
+		},
+		events: make(map[string]string),
+	}
+}
+
+func multistatus(w http.ResponseWriter, responses ...string) {
+	w.Header().Set("Content-Type", "application/xml; charset=utf-8")
+	w.WriteHeader(http.StatusMultiStatus)
+	fmt.Fprintf(w, `<?xml version="1.0" encoding="utf-8"?>
+<d:multistatus xmlns:d="DAV:" xmlns:c="urn:ietf:params:xml:ns:caldav">%s</d:multistatus>`, strings.Join(responses, ""))
+}
+
+func response(href string, props string) string {
+	return fmt.Sprintf(`<d:response><d:href>%s</d:href><d:propstat><d:prop>%s</d:prop><d:status>HTTP/1.1 200 OK</d:status></d:propstat></d:response>`, href, props)
+}
+
+var timeRangePattern = regexp.MustCompile(`time-range start="(\w+)" end="(\w+)"`)
+
+func (f *fakeCalDAV) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if user, password, ok := r.BasicAuth(); !ok || user != "jan" || password != "app-password" {
+		w.WriteHeader(http.StatusUnauthorized)
+		return
+	}
+
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	body, _ := io.ReadAll(r.Body)
+
+	switch {
+	case r.Method == "PROPFIND" && r.URL.Path == "/dav/":
+		multistatus(w, response("/dav/", `<d:current-user-principal><d:href>/dav/principals/jan/</d:href></d:current-user-principal>`))
+
+	case r.Method == "PROPFIND" && r.URL.Path == "/dav/principals/jan/":
+		multistatus(w, response("/dav/principals/jan/", `<c:calendar-home-set><d:href>/dav/calendars/jan/</d:href></c:calendar-home-set>`))
+
+	case r.Method == "PROPFIND" && r.URL.Path == "/dav/calendars/jan/":
+		responses := []string{response("/dav/calendars/jan/", `<d:resourcetype><d:collection/></d:resourcetype>`)}
+
+		for href, name := range f.calendars {
+			responses = append(responses, response(href, fmt.Sprintf(`<d:resourcetype><d:collection/><c:calendar/></d:resourcetype><d:displayname>%s</d:displayname>`, name)))
+		}
+
+		multistatus(w, responses...)
+
+	case r.Method == "REPORT":
+		var start, end time.Time
+
+		if match := timeRangePattern.FindSubmatch(body); match != nil {
+			start, _ = time.Parse("20060102T150405Z", string(match[1]))
+			end, _ = time.Parse("20060102T150405Z", string(match[2]))
+		}
+
+		responses := []string{}
+
+		for href, contents := range f.events {
+			if !strings.HasPrefix(href, r.URL.Path) {
+				continue
+			}
+
+			events, _ := ics.Parse(strings.NewReader(contents))
+
+			if !start.IsZero() && (!events[0].End.After(start) || !events[0].Start.Before(end)) {
+				continue
+			}
+
+			var escaped strings.Builder
+			xml.EscapeText(&escaped, []byte(contents))
+			responses = append(responses, response(href, fmt.Sprintf(`<d:getetag>"1"</d:getetag><c:calendar-data>%s</c:calendar-data>`, escaped.String())))
+		}
+
+		multistatus(w, responses...)
+
+	case r.Method == http.MethodPut:
+		if _, exists := f.events[r.URL.Path]; exists && r.Header.Get("If-None-Match") == "*" {
+			w.WriteHeader(http.StatusPreconditionFailed)
+			return
+		}
+
+		f.events[r.URL.Path] = string(body)
+		w.WriteHeader(http.StatusCreated)
+
+	case r.Method == http.MethodGet:
+		contents, exists := f.events[r.URL.Path]
+
+		if !exists {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+
+		io.WriteString(w, contents)
+
+	case r.Method == http.MethodDelete:
+		if _, exists := f.events[r.URL.Path]; !exists {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+
+		delete(f.events, r.URL.Path)
+		w.WriteHeader(http.StatusNoContent)
+
+	default:
+		w.WriteHeader(http.StatusNotFound)
+	}
+}
+
+func newCalDAVClient(t *testing.T, password string) (*calendar.CalDAVClient, *fakeCalDAV) {
+	fake := newFakeCalDAV()
+	server := httptest.NewServer(fake)
+	t.Cleanup(server.Close)
+
+	client, err := calendar.NewCalDAVClient(calendar.CalDAVCredentials{
+		URL:      server.URL + "/dav/",
+		Username: "jan",
+		Password: password,
+	}, server.Client())
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return client, fake
+}
+
+func TestCalDAVWrongPassword(t *testing.T) {
+	client, _ := newCalDAVClient(t, "hunter2")
+
+	_, err := client.ListCalendars(context.Background())
+
+	if !errors.Is(err, calendar.ErrCalDAVUnauthorized) {
+		t.Errorf("expected an unauthorized error, got %v", err)
+	}
+}
+
+func TestCalDAVListCalendars(t *testing.T) {
+	client, _ := newCalDAVClient(t, "app-password")
+	ctx := context.Background()
+
+	id, err := calendar.FindCalendarIdByName(ctx, client, "Werk")
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if !strings.HasSuffix(id, "/dav/calendars/jan/werk/") {
+		t.Errorf("unexpected calendar id %s", id)
+	}
+
+	calendars, err := client.ListCalendars(ctx)
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(calendars) != 2 {
+		t.Errorf("expected 2 calendars, got %+v", calendars)
+	}
+}
+
+func TestCalDAVEvents(t *testing.T) {
+	client, fake := newCalDAVClient(t, "app-password")
+	ctx := context.Background()
+
+	id, err := calendar.FindCalendarIdByName(ctx, client, "Werk")
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	identity := calendar.Identity{Importer: calendar.ImporterId, RosterDate: "2024-01-05", ShiftCode: "d", SourceHash: "abc123"}
+
+	dag, err := client.CreateEvent(ctx, id, &calendar.CalendarEvent{
+		Title:    "Dag",
+		Start:    time.Date(2024, 1, 5, 7, 45, 0, 0, time.Local),
+		End:      time.Date(2024, 1, 5, 16, 15, 0, 0, time.Local),
+		Identity: &identity,
+	})
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	_, err = client.CreateEvent(ctx, id, &calendar.CalendarEvent{
+		Title:  "Vakantie",
+		Start:  time.Date(2024, 3, 1, 0, 0, 0, 0, time.UTC),
+		End:    time.Date(2024, 3, 2, 0, 0, 0, 0, time.UTC),
+		AllDay: true,
+	})
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	events, err := client.ListEvents(ctx, id, time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC), time.Date(2024, 2, 1, 0, 0, 0, 0, time.UTC))
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(events) != 1 || events[0].Title != "Dag" || events[0].Id != dag.Id || !events[0].Start.Equal(dag.Start) {
+		t.Fatalf("expected only the created Dag event in january, got %+v", events)
+	}
+
+	if events[0].Identity == nil || *events[0].Identity != identity {
+		t.Errorf("expected the identity to be stored with the event, got %+v", events[0].Identity)
+	}
+
+	before, _ := ics.Parse(strings.NewReader(fake.event(t, dag.Id)))
+
+	dag.Title = "Avond"
+
+	if _, err := client.UpdateEvent(ctx, id, dag); err != nil {
+		t.Fatal(err)
+	}
+
+	after, _ := ics.Parse(strings.NewReader(fake.event(t, dag.Id)))
+
+	if after[0].Title != "Avond" || after[0].UID != before[0].UID {
+		t.Errorf("expected the title to change and the UID to stay the same, got %+v (was %+v)", after[0], before[0])
+	}
+
+	if err := client.DeleteEvent(ctx, id, dag.Id); err != nil {
+		t.Fatal(err)
+	}
+
+	events, err = client.ListEvents(ctx, id, time.Time{}, time.Time{})
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(events) != 1 || events[0].Title != "Vakantie" || !events[0].AllDay {
+		t.Errorf("expected only the Vakantie event to remain, got %+v", events)
+	}
+}
+
+// event returns the stored contents of an event, given the URL of the event.
+func (f *fakeCalDAV) event(t *testing.T, id string) string {
+	u, err := url.Parse(id)
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	return f.events[u.Path]
+}
+
+func TestCalDAVCreateAgain(t *testing.T) {
+	client, fake := newCalDAVClient(t, "app-password")
+	ctx := context.Background()
+
+	id, err := calendar.FindCalendarIdByName(ctx, client, "Werk")
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	event := &calendar.CalendarEvent{
+		Id:    "0123456789abcdef0123456789abcdef",
+		Title: "Dag",
+		Start: time.Date(2024, 1, 5, 7, 45, 0, 0, time.Local),
+		End:   time.Date(2024, 1, 5, 16, 15, 0, 0, time.Local),
+	}
+
+	// the second create is a retry of the first, of which the response got lost
+	for i := 0; i < 2; i++ {
+		created, err := client.CreateEvent(ctx, id, event)
+
+		if err != nil {
+			t.Fatalf("expected creating the event again to succeed, got %v", err)
+		}
+
+		if !strings.HasSuffix(created.Id, "/werk/0123456789abcdef0123456789abcdef.ics") {
+			t.Errorf("expected the event to be stored under its id, got %s", created.Id)
+		}
+	}
+
+	if len(fake.events) != 1 {
+		t.Errorf("expected a single event, got %d", len(fake.events))
+	}
+}
+
+func TestCalDAVSkipsBrokenEvents(t *testing.T) {
+	client, fake := newCalDAVClient(t, "app-password")
+	ctx := context.Background()
+
+	id, err := calendar.FindCalendarIdByName(ctx, client, "Werk")
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	_, err = client.CreateEvent(ctx, id, &calendar.CalendarEvent{
+		Title: "Dag",
+		Start: time.Date(2024, 1, 5, 7, 45, 0, 0, time.Local),
+		End:   time.Date(2024, 1, 5, 16, 15, 0, 0, time.Local),
+	})
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	fake.mu.Lock()
+	fake.events["/dav/calendars/jan/werk/broken.ics"] = "BEGIN:VCALENDAR\r\nBEGIN:VEVENT\r\nSUMMARY:Kapot\r\nDTSTART:gisteren\r\nEND:VEVENT\r\nEND:VCALENDAR\r\n"
+	fake.mu.Unlock()
+
+	events, err := client.ListEvents(ctx, id, time.Time{}, time.Time{})
+
+	var skipped *calendar.SkippedEventsError
+
+	if !errors.As(err, &skipped) || len(skipped.Errors) != 1 {
+		t.Errorf("expected the broken event to be reported, got %v", err)
+	}
+
+	if len(events) != 1 || events[0].Title != "Dag" {
+		t.Errorf("expected the other events to be listed, got %+v", events)
+	}
+}
+
+func TestCalDAVProviderStoresNoPassword(t *testing.T) {
+	t.Setenv("XDG_CONFIG_HOME", t.TempDir())
+	t.Setenv("XDG_CACHE_HOME", t.TempDir())
+	t.Setenv(calendar.CalDAVPasswordEnv, "")
+
+	server := httptest.NewServer(newFakeCalDAV())
+	t.Cleanup(server.Close)
+
+	provider := &calendar.CalDAVProvider{Credentials: &calendar.CalDAVCredentials{
+		URL:      server.URL + "/dav/",
+		Username: "jan",
+		Password: "app-password",
+	}}
+
+	if _, err := provider.LogIn(); err != nil {
+		t.Fatal(err)
+	}
+
+	// the password is remembered while the provider is used, but not stored
+	if _, err := provider.LogIn(); err != nil || !provider.IsLoggedIn() {
+		t.Fatalf("expected to stay logged in, got %v", err)
+	}
+
+	contents, err := os.ReadFile(filepath.Join(os.Getenv("XDG_CONFIG_HOME"), "rooster-importer", "caldav.json"))
+
+	if err != nil || !strings.Contains(string(contents), "jan") || strings.Contains(string(contents), "app-password") {
+		t.Fatalf("expected only the URL and username to be stored, got %s (%v)", contents, err)
+	}
+
+	restarted := &calendar.CalDAVProvider{}
+
+	if _, err := restarted.LogIn(); err == nil || restarted.IsLoggedIn() {
+		t.Error("expected a login without a password to fail")
+	}
+
+	t.Setenv(calendar.CalDAVPasswordEnv, "app-password")
+
+	if _, err := restarted.LogIn(); err != nil || !restarted.IsLoggedIn() {
+		t.Errorf("expected to log in with the password from the environment, got %v", err)
+	}
+}
@@ -14,6 +14,7 @@ import (
 	"golang.org/x/oauth2"
 	"golang.org/x/oauth2/google"
 	"google.golang.org/api/calendar/v3"
+	"google.golang.org/api/googleapi"
 	"google.golang.org/api/option"
 )
 
@@ -39,10 +40,14 @@ type CalendarItem struct {
 }
 
 type CalendarEvent struct {
+	Id     string
 	Title  string
 	Start  time.Time
 	End    time.Time
 	AllDay bool
+
+	// Identity is only set for events that were created by the importer
+	Identity *Identity
 }
 
 func (c *CalendarClient) ListCalendars(ctx context.Context) ([]CalendarItem, error) {
@@ -68,9 +73,14 @@ func convertGoogleEventToCalendarEvent(event *calendar.Event) (*CalendarEvent, e
 	var err error
 
 	calendarEvent := CalendarEvent{
+		Id:    event.Id,
 		Title: event.Summary,
 	}
 
+	if event.ExtendedProperties != nil {
+		calendarEvent.Identity = identityFromProperties(event.ExtendedProperties.Private)
+	}
+
 	if event.Start == nil || event.End == nil {
 		return nil, fmt.Errorf("event %s has no start or end", event.Summary)
 	}
@@ -115,15 +125,33 @@ func convertGoogleEventToCalendarEvent(event *calendar.Event) (*CalendarEvent, e
 	return &calendarEvent, nil
 }
 
-func (c *CalendarClient) ListEvents(ctx context.Context, calendarId string) ([]CalendarEvent, error) {
+func (c *CalendarClient) ListEvents(ctx context.Context, calendarId string, from, to time.Time) ([]CalendarEvent, error) {
 	events := []CalendarEvent{}
 
-	err := c.srv.Events.List(calendarId).Context(ctx).Pages(ctx, func(e *calendar.Events) error {
+	skipped := &SkippedEventsError{}
+
+	call := c.srv.Events.List(calendarId).Context(ctx)
+
+	if !from.IsZero() {
+		call = call.TimeMin(from.Format(time.RFC3339))
+	}
+
+	if !to.IsZero() {
+		call = call.TimeMax(to.Format(time.RFC3339))
+	}
+
+	// Google only expands recurring events into single events within a range
+	if !from.IsZero() && !to.IsZero() {
+		call = call.SingleEvents(true)
+	}
+
+	err := call.Pages(ctx, func(e *calendar.Events) error {
 		if e.Items != nil {
 			for _, item := range e.Items {
 				event, err := convertGoogleEventToCalendarEvent(item)
 				if err != nil {
-					return err
+					skipped.Errors = append(skipped.Errors, err)
+					continue
 				}
 				events = append(events, *event)
 			}
@@ -136,15 +164,22 @@ func (c *CalendarClient) ListEvents(ctx context.Context, calendarId string) ([]C
 		return nil, fmt.Errorf("couldn't get calendar events: %w", err)
 	}
 
+	if len(skipped.Errors) > 0 {
+		return events, skipped
+	}
+
 	return events, nil
 }
 
-func (c *CalendarClient) CreateEvent(ctx context.Context, calendarId string, event *CalendarEvent) (*calendar.Event, error) {
-
+func convertCalendarEventToGoogleEvent(event *CalendarEvent) *calendar.Event {
 	googlecalendarevent := &calendar.Event{
 		Summary: event.Title,
 	}
 
+	if event.Identity != nil {
+		googlecalendarevent.ExtendedProperties = &calendar.EventExtendedProperties{Private: event.Identity.properties()}
+	}
+
 	if event.AllDay {
 		// For all day events, use the Date attribute
 		googlecalendarevent.Start = &calendar.EventDateTime{Date: event.Start.Format(time.DateOnly), TimeZone: "Europe/Amsterdam"}
@@ -154,39 +189,52 @@ func (c *CalendarClient) CreateEvent(ctx context.Context, calendarId string, eve
 		googlecalendarevent.End = &calendar.EventDateTime{DateTime: event.End.Format(time.RFC3339), TimeZone: "Europe/Amsterdam"}
 	}
 
-	gcalevent, err := c.srv.Events.Insert(calendarId, googlecalendarevent).Context(ctx).Do()
+	return googlecalendarevent
+}
+
+// CreateEvent creates the event under the Id of the event, or under an id chosen by Google when the Id is empty.
+func (c *CalendarClient) CreateEvent(ctx context.Context, calendarId string, event *CalendarEvent) (*CalendarEvent, error) {
+	gcalevent := convertCalendarEventToGoogleEvent(event)
+	gcalevent.Id = event.Id
+
+	gcalevent, err := c.srv.Events.Insert(calendarId, gcalevent).Context(ctx).Do()
+
+	var apiErr *googleapi.Error
+
+	// Google refuses an id that is taken with a conflict, see Backend.CreateEvent
+	if event.Id != "" && errors.As(err, &apiErr) && apiErr.Code == http.StatusConflict {
+		gcalevent, err = c.srv.Events.Get(calendarId, event.Id).Context(ctx).Do()
+	}
 
 	if err != nil {
 		return nil, fmt.Errorf("failed to create event: %w", err)
 	}
 
-	return gcalevent, nil
+	return convertGoogleEventToCalendarEvent(gcalevent)
 }
 
-func (c *CalendarClient) FindCalendarIdByName(ctx context.Context, calendarName string) (string, error) {
-	calendars, err := c.ListCalendars(ctx)
+func (c *CalendarClient) UpdateEvent(ctx context.Context, calendarId string, event *CalendarEvent) (*CalendarEvent, error) {
+	gcalevent, err := c.srv.Events.Update(calendarId, event.Id, convertCalendarEventToGoogleEvent(event)).Context(ctx).Do()
 
 	if err != nil {
-		return "", err
+		return nil, fmt.Errorf("failed to update event: %w", err)
 	}
 
-	id := ""
-	occurrences := 0
+	return convertGoogleEventToCalendarEvent(gcalevent)
+}
 
-	for _, item := range calendars {
-		if calendarName == item.Name {
-			id = item.Id
-			occurrences += 1
-		}
-	}
+func (c *CalendarClient) DeleteEvent(ctx context.Context, calendarId string, eventId string) error {
+	err := c.srv.Events.Delete(calendarId, eventId).Context(ctx).Do()
 
-	if occurrences == 1 {
-		return id, nil
-	} else if occurrences == 0 {
-		return "", errors.New(fmt.Sprintf("calendar name %s not found", calendarName))
-	} else {
-		return "", errors.New(fmt.Sprintf("calendar name %s is not unique (%d occurrences)", calendarName, occurrences))
+	if err != nil {
+		return fmt.Errorf("failed to delete event: %w", err)
 	}
+
+	return nil
+}
+
+func (c *CalendarClient) FindCalendarIdByName(ctx context.Context, calendarName string) (string, error) {
+	return FindCalendarIdByName(ctx, c, calendarName)
 }
 
 func StartWebserverForCallback(addr string, channel chan<- TokenReceivedMessage) {
@@ -273,7 +321,8 @@ func saveToken(path string, token *oauth2.Token) error {
 	return json.NewEncoder(f).Encode(token)
 }
 
-func tokenLocation() (string, error) {
+// CacheLocation returns the location of a file in the importer's directory in the user's cache directory.
+func CacheLocation(name string) (string, error) {
 	dir, err := os.UserCacheDir()
 	if err != nil {
 		return "", err
@@ -284,12 +333,16 @@ func tokenLocation() (string, error) {
 	fi, err := os.Stat(importerdir)
 
 	if err != nil {
-		os.Mkdir(importerdir, 0775)
+		os.MkdirAll(importerdir, 0775)
 	} else if !fi.Mode().IsDir() {
 		return "", errors.New(fmt.Sprintf("%s is not a directory", importerdir))
 	}
 
-	return fmt.Sprintf("%s/token.json", importerdir), nil
+	return fmt.Sprintf("%s/%s", importerdir, name), nil
+}
+
+func tokenLocation() (string, error) {
+	return CacheLocation("token.json")
 }
 
 func LogOut() error {
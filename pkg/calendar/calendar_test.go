@@ -61,7 +61,7 @@ func TestListEvents(t *testing.T) {
 		t.Fatal("Nereas werk not found")
 	}
 
-	events, err := client.ListEvents(context.TODO(), id)
+	events, err := client.ListEvents(context.TODO(), id, time.Time{}, time.Time{})
 
 	if err != nil {
 		t.Fatal(err)
@@ -0,0 +1,170 @@
+// Package calendartest provides an in-memory calendar backend for testing code that uses calendars.
+package calendartest
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"rooster-importer/pkg/calendar"
+	"sort"
+	"sync"
+	"time"
+)
+
+// Backend keeps calendars and their events in memory.
+type Backend struct {
+	mu        sync.Mutex
+	calendars []calendar.CalendarItem
+	events    map[string][]calendar.CalendarEvent
+	nextId    int
+}
+
+func NewBackend(calendarNames ...string) *Backend {
+	b := &Backend{events: make(map[string][]calendar.CalendarEvent)}
+
+	for i, name := range calendarNames {
+		id := fmt.Sprintf("calendar-%d", i+1)
+		b.calendars = append(b.calendars, calendar.CalendarItem{Id: id, Name: name})
+		b.events[id] = []calendar.CalendarEvent{}
+	}
+
+	return b
+}
+
+// Events returns the events in a calendar, ordered by their start time.
+func (b *Backend) Events(calendarId string) []calendar.CalendarEvent {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	events := make([]calendar.CalendarEvent, len(b.events[calendarId]))
+	copy(events, b.events[calendarId])
+
+	sort.Slice(events, func(i, j int) bool {
+		return events[i].Start.Before(events[j].Start)
+	})
+
+	return events
+}
+
+func (b *Backend) ListCalendars(ctx context.Context) ([]calendar.CalendarItem, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	return append([]calendar.CalendarItem{}, b.calendars...), nil
+}
+
+func (b *Backend) ListEvents(ctx context.Context, calendarId string, from, to time.Time) ([]calendar.CalendarEvent, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	events := []calendar.CalendarEvent{}
+
+	for _, event := range b.Events(calendarId) {
+		if !from.IsZero() && !event.End.After(from) {
+			continue
+		}
+
+		if !to.IsZero() && !event.Start.Before(to) {
+			continue
+		}
+
+		events = append(events, event)
+	}
+
+	return events, nil
+}
+
+func (b *Backend) CreateEvent(ctx context.Context, calendarId string, event *calendar.CalendarEvent) (*calendar.CalendarEvent, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if _, ok := b.events[calendarId]; !ok {
+		return nil, fmt.Errorf("calendar %s does not exist", calendarId)
+	}
+
+	created := *event
+
+	if created.Id == "" {
+		b.nextId += 1
+		created.Id = fmt.Sprintf("event-%d", b.nextId)
+	}
+
+	// creating an event with the id of an existing event returns the existing event, as the real backends do
+	for _, existing := range b.events[calendarId] {
+		if existing.Id == created.Id {
+			return &existing, nil
+		}
+	}
+
+	b.events[calendarId] = append(b.events[calendarId], created)
+
+	return &created, nil
+}
+
+func (b *Backend) UpdateEvent(ctx context.Context, calendarId string, event *calendar.CalendarEvent) (*calendar.CalendarEvent, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	for i, existing := range b.events[calendarId] {
+		if existing.Id == event.Id {
+			b.events[calendarId][i] = *event
+			updated := *event
+			return &updated, nil
+		}
+	}
+
+	return nil, fmt.Errorf("event %s does not exist", event.Id)
+}
+
+func (b *Backend) DeleteEvent(ctx context.Context, calendarId string, eventId string) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	events := b.events[calendarId]
+
+	for i, existing := range events {
+		if existing.Id == eventId {
+			b.events[calendarId] = append(events[:i], events[i+1:]...)
+			return nil
+		}
+	}
+
+	return &calendar.StatusError{Method: "DELETE", Path: eventId, Status: "404 Not Found", StatusCode: http.StatusNotFound}
+}
+
+// Provider is a calendar.Provider that logs in to a backend without asking anything.
+type Provider struct {
+	Backend  calendar.Backend
+	LoggedIn bool
+}
+
+func (p *Provider) Name() string {
+	return "Test calendar"
+}
+
+func (p *Provider) LogIn() (calendar.Backend, error) {
+	p.LoggedIn = true
+	return p.Backend, nil
+}
+
+func (p *Provider) LogOut() error {
+	p.LoggedIn = false
+	return nil
+}
+
+func (p *Provider) IsLoggedIn() bool {
+	return p.LoggedIn
+}
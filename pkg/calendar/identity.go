@@ -0,0 +1,52 @@
+package calendar
+
+// ImporterId is stored with every event that the importer creates.
+const ImporterId = "rooster-importer"
+
+// Identity tells which shift of which roster an event was created for, so that the importer can recognize its own
+// events regardless of what the user changed about them. It is stored with the event as private properties: extended
+// properties in Google Calendar and X- properties in CalDAV.
+type Identity struct {
+	Importer string
+	// RosterDate is the date of the shift in the roster, formatted as 2006-01-02
+	RosterDate string
+	// ShiftCode is the normalized contents of the roster cell
+	ShiftCode string
+	// SourceHash is the hash of the roster file the event was imported from
+	SourceHash string
+}
+
+const (
+	importerKey   = "importer"
+	rosterDateKey = "roster-date"
+	shiftCodeKey  = "shift-code"
+	sourceHashKey = "source-hash"
+)
+
+func (i *Identity) properties() map[string]string {
+	if i == nil {
+		return nil
+	}
+
+	return map[string]string{
+		importerKey:   i.Importer,
+		rosterDateKey: i.RosterDate,
+		shiftCodeKey:  i.ShiftCode,
+		sourceHashKey: i.SourceHash,
+	}
+}
+
+// identityFromProperties reads the identity from the private properties of an event. Events without an importer were
+// not created by the importer, and have no identity.
+func identityFromProperties(properties map[string]string) *Identity {
+	if properties[importerKey] == "" {
+		return nil
+	}
+
+	return &Identity{
+		Importer:   properties[importerKey],
+		RosterDate: properties[rosterDateKey],
+		ShiftCode:  properties[shiftCodeKey],
+		SourceHash: properties[sourceHashKey],
+	}
+}
@@ -0,0 +1,239 @@
+package calendar
+
+import (
+	"context"
+	"errors"
+	"math/rand"
+	"net/http"
+	"strconv"
+	"time"
+
+	"google.golang.org/api/googleapi"
+)
+
+// Retry configures how calendar calls are retried when they fail because of rate limits or server errors. Other errors
+// are returned right away.
+type Retry struct {
+	// Attempts is the maximum number of calls, including the first one
+	Attempts int
+	// BaseDelay is the delay before the first retry, which doubles for every next retry up to MaxDelay. The actual
+	// delay is picked randomly between half and the whole delay, so that concurrent calls don't retry at once.
+	BaseDelay time.Duration
+	// MaxDelay is the longest delay before a retry, also when the server asks to wait longer with Retry-After
+	MaxDelay time.Duration
+}
+
+var DefaultRetry = Retry{
+	Attempts:  6,
+	BaseDelay: 500 * time.Millisecond,
+	MaxDelay:  30 * time.Second,
+}
+
+// StatusError is returned when a calendar server responds with an unexpected HTTP status.
+type StatusError struct {
+	Method     string
+	Path       string
+	Status     string
+	StatusCode int
+	// RetryAfter is the Retry-After header of the response, if any
+	RetryAfter string
+}
+
+func (e *StatusError) Error() string {
+	return e.Method + " " + e.Path + " failed: " + e.Status
+}
+
+// rateLimitReasons are the reasons of 403 responses by Google that mean the request may be retried later
+var rateLimitReasons = map[string]bool{
+	"rateLimitExceeded":     true,
+	"userRateLimitExceeded": true,
+	"quotaExceeded":         true,
+}
+
+// temporary tells whether a call failed for a reason that may go away by itself, and how long the server asked to wait
+// before trying again. The delay is zero when the server didn't say.
+func temporary(err error) (bool, time.Duration) {
+	var apiErr *googleapi.Error
+
+	if errors.As(err, &apiErr) {
+		retry := apiErr.Code == http.StatusTooManyRequests || apiErr.Code >= 500
+
+		if apiErr.Code == http.StatusForbidden {
+			for _, item := range apiErr.Errors {
+				retry = retry || rateLimitReasons[item.Reason]
+			}
+		}
+
+		return retry, parseRetryAfter(apiErr.Header.Get("Retry-After"))
+	}
+
+	var statusErr *StatusError
+
+	if errors.As(err, &statusErr) {
+		retry := statusErr.StatusCode == http.StatusTooManyRequests || statusErr.StatusCode >= 500
+
+		return retry, parseRetryAfter(statusErr.RetryAfter)
+	}
+
+	return false, 0
+}
+
+// IsGone tells whether a call failed because the event doesn't exist (anymore).
+func IsGone(err error) bool {
+	var apiErr *googleapi.Error
+
+	if errors.As(err, &apiErr) {
+		return apiErr.Code == http.StatusNotFound || apiErr.Code == http.StatusGone
+	}
+
+	var statusErr *StatusError
+
+	if errors.As(err, &statusErr) {
+		return statusErr.StatusCode == http.StatusNotFound || statusErr.StatusCode == http.StatusGone
+	}
+
+	return false
+}
+
+// parseRetryAfter reads a Retry-After header, which is either a number of seconds or a date
+func parseRetryAfter(header string) time.Duration {
+	if header == "" {
+		return 0
+	}
+
+	if seconds, err := strconv.Atoi(header); err == nil && seconds > 0 {
+		return time.Duration(seconds) * time.Second
+	}
+
+	if date, err := http.ParseTime(header); err == nil {
+		if delay := time.Until(date); delay > 0 {
+			return delay
+		}
+	}
+
+	return 0
+}
+
+func (r Retry) backoff(attempt int) time.Duration {
+	delay := r.BaseDelay << attempt
+
+	if delay > r.MaxDelay || delay <= 0 {
+		delay = r.MaxDelay
+	}
+
+	return delay/2 + time.Duration(rand.Int63n(int64(delay/2)+1))
+}
+
+// do calls call until it succeeds, fails permanently, runs out of attempts or the context is done.
+func (r Retry) do(ctx context.Context, call func() error) error {
+	for attempt := 0; ; attempt++ {
+		err := call()
+
+		retry, delay := temporary(err)
+
+		if err == nil || !retry || attempt+1 >= r.Attempts {
+			return err
+		}
+
+		if delay == 0 {
+			delay = r.backoff(attempt)
+		} else if delay > r.MaxDelay {
+			delay = r.MaxDelay
+		}
+
+		timer := time.NewTimer(delay)
+
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return ctx.Err()
+		case <-timer.C:
+		}
+	}
+}
+
+// WithRetry retries the calls to a backend that fail because of rate limits or server errors.
+func WithRetry(backend Backend, retry Retry) Backend {
+	return &retryingBackend{backend: backend, retry: retry}
+}
+
+type retryingBackend struct {
+	backend Backend
+	retry   Retry
+}
+
+func (b *retryingBackend) ListCalendars(ctx context.Context) ([]CalendarItem, error) {
+	var calendars []CalendarItem
+
+	err := b.retry.do(ctx, func() (err error) {
+		calendars, err = b.backend.ListCalendars(ctx)
+		return err
+	})
+
+	return calendars, err
+}
+
+func (b *retryingBackend) ListEvents(ctx context.Context, calendarId string, from, to time.Time) ([]CalendarEvent, error) {
+	var events []CalendarEvent
+
+	err := b.retry.do(ctx, func() (err error) {
+		events, err = b.backend.ListEvents(ctx, calendarId, from, to)
+		return err
+	})
+
+	return events, err
+}
+
+// CreateEvent chooses the id of the event before the first attempt, so that an attempt that succeeded on the server
+// but failed on the way back doesn't create the event twice when it is retried.
+func (b *retryingBackend) CreateEvent(ctx context.Context, calendarId string, event *CalendarEvent) (*CalendarEvent, error) {
+	var created *CalendarEvent
+
+	if event.Id == "" {
+		id, err := NewEventId()
+
+		if err != nil {
+			return nil, err
+		}
+
+		withId := *event
+		withId.Id = id
+		event = &withId
+	}
+
+	err := b.retry.do(ctx, func() (err error) {
+		created, err = b.backend.CreateEvent(ctx, calendarId, event)
+		return err
+	})
+
+	return created, err
+}
+
+func (b *retryingBackend) UpdateEvent(ctx context.Context, calendarId string, event *CalendarEvent) (*CalendarEvent, error) {
+	var updated *CalendarEvent
+
+	err := b.retry.do(ctx, func() (err error) {
+		updated, err = b.backend.UpdateEvent(ctx, calendarId, event)
+		return err
+	})
+
+	return updated, err
+}
+
+// DeleteEvent treats an event that is gone as deleted when the delete is retried, because an attempt that succeeded on
+// the server but failed on the way back has deleted it already.
+func (b *retryingBackend) DeleteEvent(ctx context.Context, calendarId string, eventId string) error {
+	retried := false
+
+	return b.retry.do(ctx, func() error {
+		err := b.backend.DeleteEvent(ctx, calendarId, eventId)
+
+		if retried && IsGone(err) {
+			return nil
+		}
+
+		retried = true
+
+		return err
+	})
+}
@@ -0,0 +1,144 @@
+package calendar_test
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"rooster-importer/pkg/calendar"
+	"rooster-importer/pkg/calendar/calendartest"
+	"testing"
+	"time"
+
+	"google.golang.org/api/googleapi"
+)
+
+// flakyBackend fails the first calls to CreateEvent or DeleteEvent with the given errors. With lost, the events are created or deleted before
+// the errors are returned, as when the response to a successful call doesn't arrive.
+type flakyBackend struct {
+	*calendartest.Backend
+	errors []error
+	lost   bool
+	calls  int
+}
+
+func (b *flakyBackend) CreateEvent(ctx context.Context, calendarId string, event *calendar.CalendarEvent) (*calendar.CalendarEvent, error) {
+	b.calls += 1
+
+	if len(b.errors) > 0 {
+		err := b.errors[0]
+		b.errors = b.errors[1:]
+
+		if b.lost {
+			b.Backend.CreateEvent(ctx, calendarId, event)
+		}
+
+		return nil, err
+	}
+
+	return b.Backend.CreateEvent(ctx, calendarId, event)
+}
+
+func (b *flakyBackend) DeleteEvent(ctx context.Context, calendarId string, eventId string) error {
+	b.calls += 1
+
+	if len(b.errors) > 0 {
+		err := b.errors[0]
+		b.errors = b.errors[1:]
+
+		if b.lost {
+			b.Backend.DeleteEvent(ctx, calendarId, eventId)
+		}
+
+		return err
+	}
+
+	return b.Backend.DeleteEvent(ctx, calendarId, eventId)
+}
+
+var testRetry = calendar.Retry{Attempts: 4, BaseDelay: time.Millisecond, MaxDelay: 5 * time.Millisecond}
+
+var dag = &calendar.CalendarEvent{
+	Title: "Dag",
+	Start: time.Date(2024, 1, 5, 7, 45, 0, 0, time.Local),
+	End:   time.Date(2024, 1, 5, 16, 15, 0, 0, time.Local),
+}
+
+func TestRetryRateLimits(t *testing.T) {
+	flaky := &flakyBackend{
+		Backend: calendartest.NewBackend("Werk"),
+		errors: []error{
+			&googleapi.Error{Code: http.StatusForbidden, Errors: []googleapi.ErrorItem{{Reason: "rateLimitExceeded"}}},
+			&googleapi.Error{Code: http.StatusTooManyRequests},
+			&calendar.StatusError{Method: "PUT", Path: "/dag.ics", Status: "503 Service Unavailable", StatusCode: http.StatusServiceUnavailable},
+		},
+	}
+
+	created, err := calendar.WithRetry(flaky, testRetry).CreateEvent(context.Background(), "calendar-1", dag)
+
+	if err != nil || created.Title != "Dag" {
+		t.Fatalf("expected the event to be created after retrying, got %v", err)
+	}
+
+	if flaky.calls != 4 {
+		t.Errorf("expected 4 calls, got %d", flaky.calls)
+	}
+}
+
+func TestRetryPermanentErrors(t *testing.T) {
+	forbidden := &googleapi.Error{Code: http.StatusForbidden, Errors: []googleapi.ErrorItem{{Reason: "forbidden"}}}
+	flaky := &flakyBackend{Backend: calendartest.NewBackend("Werk"), errors: []error{forbidden}}
+
+	_, err := calendar.WithRetry(flaky, testRetry).CreateEvent(context.Background(), "calendar-1", dag)
+
+	if !errors.Is(err, forbidden) || flaky.calls != 1 {
+		t.Errorf("expected the error to be returned without retrying, got %v after %d calls", err, flaky.calls)
+	}
+}
+
+func TestRetryAfter(t *testing.T) {
+	unavailable := &googleapi.Error{Code: http.StatusServiceUnavailable, Header: http.Header{"Retry-After": []string{"120"}}}
+	flaky := &flakyBackend{Backend: calendartest.NewBackend("Werk"), errors: []error{unavailable}}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+
+	// the server asks to wait two minutes, which is capped at the MaxDelay of a few milliseconds
+	_, err := calendar.WithRetry(flaky, testRetry).CreateEvent(ctx, "calendar-1", dag)
+
+	if err != nil || flaky.calls != 2 {
+		t.Errorf("expected to retry after MaxDelay, got %v after %d calls", err, flaky.calls)
+	}
+}
+
+func TestRetryCreatesOnce(t *testing.T) {
+	unavailable := &googleapi.Error{Code: http.StatusServiceUnavailable}
+	flaky := &flakyBackend{Backend: calendartest.NewBackend("Werk"), errors: []error{unavailable, unavailable}, lost: true}
+
+	created, err := calendar.WithRetry(flaky, testRetry).CreateEvent(context.Background(), "calendar-1", dag)
+
+	if err != nil || created.Id == "" {
+		t.Fatalf("expected the event to be created after retrying, got %v", err)
+	}
+
+	if events := flaky.Events("calendar-1"); len(events) != 1 || flaky.calls != 3 {
+		t.Errorf("expected a single event after 3 calls, got %d events after %d calls", len(events), flaky.calls)
+	}
+}
+
+func TestRetryDeleteGone(t *testing.T) {
+	unavailable := &googleapi.Error{Code: http.StatusServiceUnavailable}
+	flaky := &flakyBackend{Backend: calendartest.NewBackend("Werk"), errors: []error{unavailable}, lost: true}
+	backend := calendar.WithRetry(flaky, testRetry)
+
+	created, _ := flaky.Backend.CreateEvent(context.Background(), "calendar-1", dag)
+
+	// the first attempt deletes the event, so the retry finds it gone
+	if err := backend.DeleteEvent(context.Background(), "calendar-1", created.Id); err != nil || flaky.calls != 2 {
+		t.Errorf("expected the retried delete to succeed, got %v after %d calls", err, flaky.calls)
+	}
+
+	// without a retry, an event that is gone was never there
+	if err := backend.DeleteEvent(context.Background(), "calendar-1", created.Id); !calendar.IsGone(err) {
+		t.Errorf("expected deleting a missing event to fail, got %v", err)
+	}
+}
@@ -0,0 +1,624 @@
+package cli
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"os/signal"
+	"rooster-importer/pkg/calendar"
+	"rooster-importer/pkg/domain"
+	"rooster-importer/pkg/excelreader"
+	"strings"
+	"time"
+)
+
+const usage = `Usage: rooster-importer <command> [flags]
+
+Without a command, the graphical application is started.
+
+Commands:
+  parse      convert a roster file and print the resulting events
+  preview    convert a roster file and print which events are not yet in a calendar
+  import     import the events of a roster file into a calendar
+  export     write the events of a roster file to an .ics file
+  calendars  list the calendars that events can be imported into
+  layouts    list the layout profiles that rosters can be read with
+  names      list the people in a roster file
+  team       write the events of everyone in a roster file to an .ics file per person
+  undo       delete the events that were created by the last import
+
+Run rooster-importer <command> -h for the flags of a command.
+`
+
+// runner drives a domain.Application the same way the UI does, but prints the messages of the application instead of
+// displaying them.
+type runner struct {
+	app    *domain.Application
+	state  domain.UIState
+	failed bool
+	// ctx is canceled on ctrl-c, which stops the calls to the calendar
+	ctx context.Context
+
+	stdout io.Writer
+	stderr io.Writer
+}
+
+type options struct {
+	file     string
+	name     string
+	layout   string
+	sheets   string
+	from     string
+	to       string
+	calendar string
+	out      string
+
+	backend     string
+	caldavURL   string
+	caldavUser  string
+	concurrency int
+	dryRun      bool
+	json        bool
+	overwrite   bool
+	strict      bool
+	sync        bool
+}
+
+// CommandArgs returns the arguments for Run when the arguments of the program (without its name) start with a command
+// or ask for help. Otherwise the graphical application should be started, and ok is false. The -psn_… argument that
+// macOS passes to an application that is started from Finder is left out.
+func CommandArgs(args []string) (commandArgs []string, ok bool) {
+	for _, arg := range args {
+		if !strings.HasPrefix(arg, "-psn_") {
+			commandArgs = append(commandArgs, arg)
+		}
+	}
+
+	if len(commandArgs) == 0 || !isCommand(commandArgs[0]) {
+		return nil, false
+	}
+
+	return commandArgs, true
+}
+
+func isCommand(arg string) bool {
+	switch arg {
+	case "parse", "preview", "import", "export", "names", "team", "calendars", "undo", "layouts", "help", "-h", "--help":
+		return true
+	}
+
+	return false
+}
+
+// Run executes the command given in args (without the program name), and returns the exit code of the program.
+func Run(args []string) int {
+	r := &runner{
+		stdout: os.Stdout,
+		stderr: os.Stderr,
+	}
+
+	return r.run(args)
+}
+
+func (r *runner) run(args []string) int {
+	if len(args) == 0 {
+		fmt.Fprint(r.stderr, usage)
+		return 2
+	}
+
+	command, args := args[0], args[1:]
+
+	flags := flag.NewFlagSet(command, flag.ContinueOnError)
+	flags.SetOutput(r.stderr)
+
+	opts := options{}
+
+	switch command {
+	case "parse", "preview", "import", "export":
+		flags.StringVar(&opts.file, "file", "", "roster file to read")
+		flags.StringVar(&opts.name, "name", "", "name in the first column of the roster")
+		flags.BoolVar(&opts.strict, "strict", false, "fail when shifts are not recognized and defaulted")
+		flags.StringVar(&opts.layout, "layout", "", "layout profile to read the roster with, instead of the selected profile")
+	case "names", "team":
+		flags.StringVar(&opts.file, "file", "", "roster file to read")
+		flags.StringVar(&opts.layout, "layout", "", "layout profile to read the roster with, instead of the selected profile")
+	case "calendars", "undo", "layouts":
+	case "help", "-h", "--help":
+		fmt.Fprint(r.stdout, usage)
+		return 0
+	default:
+		fmt.Fprintf(r.stderr, "unknown command %s\n\n%s", command, usage)
+		return 2
+	}
+
+	if command == "preview" || command == "import" {
+		flags.StringVar(&opts.calendar, "calendar", "", "name of the calendar to compare with or import into")
+		flags.BoolVar(&opts.sync, "sync", false, "also update and delete events that were imported before and changed in the roster")
+		flags.BoolVar(&opts.json, "json", false, "print the import plan as JSON instead of the summary (with import, only together with --dry-run)")
+	}
+
+	if command == "preview" || command == "import" || command == "calendars" || command == "undo" {
+		flags.StringVar(&opts.backend, "backend", "google", "calendar backend to use: google or caldav")
+		flags.StringVar(&opts.caldavURL, "caldav-url", "", "URL of the CalDAV server (the password is read from $"+calendar.CalDAVPasswordEnv+")")
+		flags.StringVar(&opts.caldavUser, "caldav-user", "", "username on the CalDAV server")
+	}
+
+	if command == "import" {
+		flags.BoolVar(&opts.dryRun, "dry-run", false, "show what would be imported without creating events")
+		flags.IntVar(&opts.concurrency, "concurrency", domain.DefaultConcurrency, "number of events to write to the calendar at the same time")
+	}
+
+	if command == "export" {
+		flags.StringVar(&opts.out, "out", "rooster.ics", "file to write the events to")
+	}
+
+	if command == "team" {
+		flags.StringVar(&opts.out, "out", ".", "directory to write the .ics files to")
+		flags.BoolVar(&opts.overwrite, "overwrite", false, "overwrite .ics files that already exist in the directory")
+	}
+
+	if command == "parse" || command == "preview" || command == "import" || command == "export" || command == "team" {
+		flags.StringVar(&opts.sheets, "sheets", "", "comma separated sheets to read, instead of all sheets")
+		flags.StringVar(&opts.from, "from", "", "only convert the shifts from this date (yyyy-mm-dd or today)")
+		flags.StringVar(&opts.to, "to", "", "only convert the shifts until and including this date (yyyy-mm-dd or today)")
+	}
+
+	if err := flags.Parse(args); err != nil {
+		if errors.Is(err, flag.ErrHelp) {
+			return 0
+		}
+		return 2
+	}
+
+	if r.app == nil {
+		provider, err := newProvider(opts)
+
+		if err != nil {
+			fmt.Fprintf(r.stderr, "error: %s\n", err)
+			return 2
+		}
+
+		r.app = domain.NewApplication(provider)
+	}
+
+	// stop talking to the calendar on ctrl-c, after which an import lists the events that were created before
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+
+	r.ctx = ctx
+
+	var err error
+
+	switch command {
+	case "parse":
+		err = r.parse(opts)
+	case "preview":
+		err = r.preview(opts)
+	case "import":
+		err = r.importEvents(opts)
+	case "export":
+		err = r.export(opts)
+	case "calendars":
+		err = r.calendars()
+	case "undo":
+		err = r.undo()
+	case "layouts":
+		err = r.layouts()
+	case "names":
+		err = r.names(opts)
+	case "team":
+		err = r.team(opts)
+	}
+
+	if err != nil {
+		fmt.Fprintf(r.stderr, "error: %s\n", err)
+		return 1
+	}
+
+	if r.failed {
+		return 1
+	}
+
+	return 0
+}
+
+func newProvider(opts options) (calendar.Provider, error) {
+	switch opts.backend {
+	case "google", "":
+		return calendar.GoogleProvider{}, nil
+	case "caldav":
+		provider := &calendar.CalDAVProvider{}
+
+		// without a URL, the login of a previous run is used
+		if opts.caldavURL != "" {
+			provider.Credentials = &calendar.CalDAVCredentials{
+				URL:      opts.caldavURL,
+				Username: opts.caldavUser,
+				Password: os.Getenv(calendar.CalDAVPasswordEnv),
+			}
+		}
+
+		return provider, nil
+	default:
+		return nil, fmt.Errorf("unknown backend %s", opts.backend)
+	}
+}
+
+// dispatch runs an action, and handles the messages it sends until the action is done.
+func (r *runner) dispatch(action domain.Action) {
+	done := make(chan struct{})
+
+	go func() {
+		action(r.app)
+		close(done)
+	}()
+
+	for {
+		select {
+		case msg := <-r.app.GuiStuff():
+			r.handle(msg)
+		case <-done:
+			return
+		}
+	}
+}
+
+func (r *runner) handle(msg interface{}) {
+	switch m := msg.(type) {
+	case error:
+		fmt.Fprintf(r.stderr, "error: %s\n", m)
+		r.failed = true
+
+		var ambiguous *excelreader.AmbiguousNameError
+
+		if errors.As(m, &ambiguous) {
+			fmt.Fprintln(r.stderr, "use --name with the full name of one of them")
+		}
+
+		var existing *domain.ExistingFilesError
+
+		if errors.As(m, &existing) {
+			fmt.Fprintln(r.stderr, "use --overwrite to replace them, or another --out directory")
+		}
+
+	case domain.Information:
+		fmt.Fprintln(r.stdout, string(m))
+
+	case domain.NewState:
+		r.state = domain.UIState(m)
+
+	case domain.Progress:
+		if !m.Finished {
+			fmt.Fprintf(r.stderr, "\r%d/%d", m.Done, m.Total)
+			return
+		}
+
+		fmt.Fprintln(r.stderr)
+
+		for _, result := range m.Results {
+			if result.Err != nil {
+				fmt.Fprintf(r.stdout, "failed to %s %s: %s\n", result.Change, result.Event.Summary(), result.Err)
+			} else {
+				fmt.Fprintf(r.stdout, "%sd %s\n", result.Change, result.Event.Summary())
+			}
+		}
+
+	default:
+		panic(fmt.Sprintf("unexpected event to CLI (type %T)", m))
+	}
+}
+
+// readFile converts the roster file given in the options, and checks whether the conversion went well enough to
+// continue.
+func (r *runner) readFile(opts options) error {
+	if opts.file == "" || opts.name == "" {
+		return errors.New("--file and --name are required")
+	}
+
+	if err := r.openRoster(opts); err != nil {
+		return err
+	}
+
+	r.dispatch(domain.SelectNameAction(r.ctx, opts.name))
+
+	if r.failed {
+		return errors.New("cannot convert roster file")
+	}
+
+	return nil
+}
+
+// openRoster reads the roster file given in the options, and selects the layout, sheets and dates to read it with.
+func (r *runner) openRoster(opts options) error {
+	from, err := parseDate(opts.from)
+
+	if err != nil {
+		return err
+	}
+
+	to, err := parseDate(opts.to)
+
+	if err != nil {
+		return err
+	}
+
+	file, err := os.Open(opts.file)
+
+	if err != nil {
+		return err
+	}
+
+	r.dispatch(domain.LoadShiftMappingAction())
+	r.dispatch(domain.LoadLayoutAction())
+
+	if opts.layout != "" {
+		r.dispatch(domain.UseLayoutAction(r.ctx, opts.layout))
+	}
+
+	r.dispatch(domain.SelectedXlsxFileAction(r.ctx, file, opts.file, ""))
+
+	if opts.sheets != "" {
+		sheets := []string{}
+
+		for _, sheet := range strings.Split(opts.sheets, ",") {
+			sheets = append(sheets, strings.TrimSpace(sheet))
+		}
+
+		r.dispatch(domain.SelectSheetsAction(r.ctx, sheets))
+	}
+
+	if !from.IsZero() || !to.IsZero() {
+		r.dispatch(domain.SetDateRangeAction(r.ctx, from, to))
+	}
+
+	if r.failed {
+		return errors.New("cannot read roster file")
+	}
+
+	return nil
+}
+
+// parseDate reads a date given on the command line, which is the zero time when it is empty.
+func parseDate(value string) (time.Time, error) {
+	switch value {
+	case "":
+		return time.Time{}, nil
+	case "today":
+		now := time.Now()
+		return time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, time.Local), nil
+	}
+
+	date, err := time.ParseInLocation("2006-01-02", value, time.Local)
+
+	if err != nil {
+		return time.Time{}, fmt.Errorf("invalid date %s, use yyyy-mm-dd or today", value)
+	}
+
+	return date, nil
+}
+
+func (r *runner) checkStrict(opts options) error {
+	if opts.strict && len(r.state.WarningEvents) > 0 {
+		return fmt.Errorf("%d shifts were not recognized (--strict)", len(r.state.WarningEvents))
+	}
+
+	return nil
+}
+
+func (r *runner) selectCalendar(name string) error {
+	if name == "" {
+		return errors.New("--calendar is required")
+	}
+
+	r.dispatch(domain.SelectCalendarAction(r.ctx, name))
+
+	if r.failed {
+		return fmt.Errorf("cannot use calendar %s", name)
+	}
+
+	return nil
+}
+
+func (r *runner) parse(opts options) error {
+	if err := r.readFile(opts); err != nil {
+		return err
+	}
+
+	for _, event := range r.state.ConvertedEvents {
+		fmt.Fprintln(r.stdout, event.Summary())
+	}
+
+	return r.checkStrict(opts)
+}
+
+func (r *runner) preview(opts options) error {
+	if err := r.readFile(opts); err != nil {
+		return err
+	}
+
+	r.dispatch(domain.SetSyncModeAction(opts.sync))
+
+	if opts.calendar != "" || opts.json {
+		if err := r.selectCalendar(opts.calendar); err != nil {
+			return err
+		}
+	}
+
+	if opts.json {
+		if err := r.printPlan(); err != nil {
+			return err
+		}
+	} else {
+		fmt.Fprint(r.stdout, r.state.Summary())
+	}
+
+	return r.checkStrict(opts)
+}
+
+// printPlan prints the plan of importing into the selected calendar as JSON.
+func (r *runner) printPlan() error {
+	plan, err := r.state.Plan.JSON()
+
+	if err != nil {
+		return err
+	}
+
+	fmt.Fprintln(r.stdout, string(plan))
+
+	return nil
+}
+
+func (r *runner) importEvents(opts options) error {
+	if opts.json && !opts.dryRun {
+		return errors.New("--json can only be used together with --dry-run")
+	}
+
+	if err := r.readFile(opts); err != nil {
+		return err
+	}
+
+	r.dispatch(domain.SetSyncModeAction(opts.sync))
+
+	if err := r.selectCalendar(opts.calendar); err != nil {
+		return err
+	}
+
+	if opts.json {
+		if err := r.printPlan(); err != nil {
+			return err
+		}
+
+		return r.checkStrict(opts)
+	}
+
+	fmt.Fprint(r.stdout, r.state.Summary())
+
+	if err := r.checkStrict(opts); err != nil {
+		return err
+	}
+
+	if opts.dryRun {
+		fmt.Fprintf(r.stdout, "\nDry run: %d events would be created, %d updated and %d deleted in %s\n",
+			len(r.state.EventsNotAlreadyInCalendar), len(r.state.EventsToUpdate), len(r.state.EventsToDelete), opts.calendar)
+		return nil
+	}
+
+	if len(r.state.EventsNotAlreadyInCalendar)+len(r.state.EventsToUpdate)+len(r.state.EventsToDelete) == 0 {
+		fmt.Fprintln(r.stdout, "\nNothing to import")
+		return nil
+	}
+
+	r.dispatch(domain.SetConcurrencyAction(opts.concurrency))
+	r.dispatch(domain.ImportEntriesToCalendar(r.ctx))
+
+	return nil
+}
+
+func (r *runner) export(opts options) error {
+	if err := r.readFile(opts); err != nil {
+		return err
+	}
+
+	if err := r.checkStrict(opts); err != nil {
+		return err
+	}
+
+	file, err := os.Create(opts.out)
+
+	if err != nil {
+		return err
+	}
+
+	r.dispatch(domain.ExportIcsAction(file, opts.out))
+
+	return nil
+}
+
+func (r *runner) calendars() error {
+	r.dispatch(domain.ClickedCalendarLoginAction(r.ctx))
+
+	for _, name := range r.state.AvailableCalendars {
+		fmt.Fprintln(r.stdout, name)
+	}
+
+	return nil
+}
+
+func (r *runner) undo() error {
+	r.dispatch(domain.GuiAttachedAction(r.ctx))
+
+	if r.state.LastImport == "" {
+		return errors.New("there is no import to undo")
+	}
+
+	fmt.Fprintf(r.stdout, "Removing the %s\n", r.state.LastImport)
+
+	r.dispatch(domain.UndoLastImportAction(r.ctx))
+
+	return nil
+}
+
+// layouts lists the layout profiles, and marks the profile that is used without --layout.
+func (r *runner) layouts() error {
+	r.dispatch(domain.LoadLayoutAction())
+
+	for _, name := range r.state.Layouts {
+		if name == r.state.SelectedLayout {
+			fmt.Fprintf(r.stdout, "%s (selected)\n", name)
+		} else {
+			fmt.Fprintln(r.stdout, name)
+		}
+	}
+
+	return nil
+}
+
+// names lists the people in the roster file, which can be given as --name to the other commands.
+func (r *runner) names(opts options) error {
+	if opts.file == "" {
+		return errors.New("--file is required")
+	}
+
+	file, err := os.Open(opts.file)
+
+	if err != nil {
+		return err
+	}
+
+	r.dispatch(domain.LoadLayoutAction())
+
+	if opts.layout != "" {
+		r.dispatch(domain.UseLayoutAction(r.ctx, opts.layout))
+	}
+
+	r.dispatch(domain.SelectedXlsxFileAction(r.ctx, file, opts.file, ""))
+
+	if r.failed {
+		return errors.New("cannot read roster file")
+	}
+
+	for _, name := range r.state.Names {
+		fmt.Fprintln(r.stdout, name)
+	}
+
+	return nil
+}
+
+// team writes the events of everyone in the roster file to an .ics file per person, and lists the shifts that were not
+// recognized for each of them.
+func (r *runner) team(opts options) error {
+	if opts.file == "" {
+		return errors.New("--file is required")
+	}
+
+	if err := r.openRoster(opts); err != nil {
+		return err
+	}
+
+	r.dispatch(domain.ExportTeamIcsAction(opts.out, opts.overwrite))
+
+	return nil
+}
@@ -0,0 +1,266 @@
+package cli
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"rooster-importer/pkg/calendar/calendartest"
+	"rooster-importer/pkg/domain"
+	"strings"
+	"testing"
+
+	"github.com/xuri/excelize/v2"
+)
+
+// writeRoster creates a small roster with two weeks of shifts for a single person.
+func writeRoster(t *testing.T, shifts []string) string {
+	t.Helper()
+
+	file := excelize.NewFile()
+	sheet := file.GetSheetName(0)
+
+	file.SetCellValue(sheet, "A2", "Jan de Vries")
+
+	for i, shift := range shifts {
+		datecell, _ := excelize.CoordinatesToCellName(i+2, 1)
+		shiftcell, _ := excelize.CoordinatesToCellName(i+2, 2)
+
+		file.SetCellValue(sheet, datecell, fmt.Sprintf("2024-1-%d", i+1))
+		file.SetCellValue(sheet, shiftcell, shift)
+	}
+
+	path := filepath.Join(t.TempDir(), "rooster.xlsx")
+
+	if err := file.SaveAs(path); err != nil {
+		t.Fatal(err)
+	}
+
+	return path
+}
+
+func newTestRunner(t *testing.T) (*runner, *bytes.Buffer, *bytes.Buffer) {
+	// don't pick up the mapping file of whoever runs the tests
+	t.Setenv("XDG_CONFIG_HOME", t.TempDir())
+	t.Setenv("XDG_CACHE_HOME", t.TempDir())
+	t.Setenv("HOME", t.TempDir())
+
+	stdout, stderr := &bytes.Buffer{}, &bytes.Buffer{}
+	r := &runner{stdout: stdout, stderr: stderr}
+	r.app = domain.NewApplication(&calendartest.Provider{Backend: calendartest.NewBackend("Werk")})
+
+	return r, stdout, stderr
+}
+
+func TestParse(t *testing.T) {
+	r, stdout, stderr := newTestRunner(t)
+	path := writeRoster(t, strings.Split("d t a n x x x d d d d d x x", " "))
+
+	code := r.run([]string{"parse", "--file", path, "--name", "Jan"})
+
+	if code != 0 {
+		t.Fatalf("exit code %d: %s", code, stderr)
+	}
+
+	lines := strings.Split(strings.TrimSpace(stdout.String()), "\n")
+
+	// the free days in the weekend (6 and 7 january, 13 and 14 january) are skipped
+	if len(lines) != 10 {
+		t.Errorf("expected 10 events, got %d:\n%s", len(lines), stdout)
+	}
+
+	if lines[0] != "Dag: 01/01 (07:45 - 16:15)" {
+		t.Errorf("unexpected first event %q", lines[0])
+	}
+}
+
+func TestParseStrict(t *testing.T) {
+	r, _, stderr := newTestRunner(t)
+	path := writeRoster(t, strings.Split("d t a n ??? x x d d d d d x x", " "))
+
+	if code := r.run([]string{"parse", "--file", path, "--name", "Jan", "--strict"}); code != 1 {
+		t.Errorf("expected exit code 1 for unknown shifts, got %d", code)
+	}
+
+	if !strings.Contains(stderr.String(), "not recognized") {
+		t.Errorf("expected an error about unrecognized shifts, got %q", stderr)
+	}
+}
+
+func TestUnknownCommand(t *testing.T) {
+	r, _, _ := newTestRunner(t)
+
+	if code := r.run([]string{"frobnicate"}); code != 2 {
+		t.Errorf("expected exit code 2, got %d", code)
+	}
+}
+
+func TestCommandArgs(t *testing.T) {
+	tests := []struct {
+		args []string
+		ok   bool
+	}{
+		{[]string{}, false},
+		{[]string{"-psn_0_12345"}, false},
+		{[]string{"frobnicate"}, false},
+		{[]string{"--help"}, true},
+		{[]string{"-psn_0_12345", "parse", "--file", "rooster.xlsx"}, true},
+	}
+
+	for _, test := range tests {
+		args, ok := CommandArgs(test.args)
+
+		if ok != test.ok {
+			t.Errorf("%v: expected %v, got %v", test.args, test.ok, ok)
+		}
+
+		for _, arg := range args {
+			if strings.HasPrefix(arg, "-psn_") {
+				t.Errorf("%v: expected the -psn_ argument to be left out, got %v", test.args, args)
+			}
+		}
+	}
+}
+
+func TestExport(t *testing.T) {
+	r, stdout, stderr := newTestRunner(t)
+	path := writeRoster(t, strings.Split("d t a n x x x d d d d d x x", " "))
+	out := filepath.Join(t.TempDir(), "rooster.ics")
+
+	if code := r.run([]string{"export", "--file", path, "--name", "Jan", "--out", out}); code != 0 {
+		t.Fatalf("exit code %d: %s", code, stderr)
+	}
+
+	contents, err := os.ReadFile(out)
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if count := strings.Count(string(contents), "BEGIN:VEVENT"); count != 10 {
+		t.Errorf("expected 10 events in %s, got %d", out, count)
+	}
+
+	if !strings.Contains(stdout.String(), "Saved 10 events") {
+		t.Errorf("expected a confirmation, got %q", stdout)
+	}
+}
+
+func TestPreviewJSON(t *testing.T) {
+	r, stdout, stderr := newTestRunner(t)
+	path := writeRoster(t, strings.Split("d t a n x x x d d d d d x x", " "))
+
+	if code := r.run([]string{"preview", "--file", path, "--name", "Jan", "--calendar", "Werk", "--json"}); code != 0 {
+		t.Fatalf("exit code %d: %s", code, stderr)
+	}
+
+	plan := domain.ImportPlan{}
+
+	if err := json.Unmarshal(stdout.Bytes(), &plan); err != nil {
+		t.Fatalf("expected only the plan as JSON, got %v:\n%s", err, stdout)
+	}
+
+	if plan.Calendar != "Werk" || plan.Count(domain.PlanCreate) != 10 {
+		t.Errorf("expected 10 events to be created in Werk, got %+v", plan)
+	}
+}
+
+func TestLayoutFlag(t *testing.T) {
+	r, stdout, stderr := newTestRunner(t)
+	path := writeRoster(t, strings.Split("d t a n x x x d d d d d x x", " "))
+
+	dir := filepath.Join(os.Getenv("XDG_CONFIG_HOME"), "rooster-importer")
+
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		t.Fatal(err)
+	}
+
+	profiles := `{"profiles": [{"name": "verticaal", "orientation": "vertical"}]}`
+
+	if err := os.WriteFile(filepath.Join(dir, "layouts.json"), []byte(profiles), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	if code := r.run([]string{"layouts"}); code != 0 {
+		t.Fatalf("expected exit code 0, got %d: %s", code, stderr)
+	}
+
+	if stdout.String() != "auto (selected)\nverticaal\n" {
+		t.Errorf("expected the profiles to be listed, got %q", stdout)
+	}
+
+	stdout.Reset()
+
+	if code := r.run([]string{"parse", "--file", path, "--name", "Jan", "--layout", "verticaal"}); code != 0 {
+		t.Fatalf("expected exit code 0, got %d: %s", code, stderr)
+	}
+
+	if !strings.Contains(stdout.String(), "didn't find") {
+		t.Errorf("expected no entries with the vertical profile, got %s", stdout)
+	}
+
+	if code := r.run([]string{"parse", "--file", path, "--name", "Jan", "--layout", "schuin"}); code == 0 {
+		t.Error("expected an unknown profile to fail")
+	}
+
+	if !strings.Contains(stderr.String(), "unknown layout profile schuin") {
+		t.Errorf("expected the unknown profile to be reported, got %s", stderr)
+	}
+}
+
+func TestNames(t *testing.T) {
+	r, stdout, stderr := newTestRunner(t)
+	path := writeRoster(t, strings.Split("d t a n x x x d d d d d x x", " "))
+
+	if code := r.run([]string{"names", "--file", path}); code != 0 {
+		t.Fatalf("expected exit code 0, got %d: %s", code, stderr)
+	}
+
+	if stdout.String() != "Jan de Vries\n" {
+		t.Errorf("expected the people in the roster, got %q", stdout)
+	}
+}
+
+func TestTeam(t *testing.T) {
+	r, stdout, stderr := newTestRunner(t)
+	path := writeRoster(t, strings.Split("d t a n x x x d d d q d x x", " "))
+	out := t.TempDir()
+
+	if code := r.run([]string{"team", "--file", path, "--out", out}); code != 0 {
+		t.Fatalf("expected exit code 0, got %d: %s", code, stderr)
+	}
+
+	if _, err := os.Stat(filepath.Join(out, "jan-de-vries.ics")); err != nil {
+		t.Errorf("expected the roster of Jan de Vries to be written: %s", err)
+	}
+
+	if !strings.Contains(stdout.String(), "Jan de Vries: 10 events in jan-de-vries.ics, unmapped codes: q") {
+		t.Errorf("expected a summary of the roster of Jan de Vries, got %q", stdout)
+	}
+}
+
+func TestSheetsAndDates(t *testing.T) {
+	r, stdout, stderr := newTestRunner(t)
+	path := writeRoster(t, strings.Split("d t a n x x x d d d d d x x", " "))
+
+	code := r.run([]string{"parse", "--file", path, "--name", "Jan", "--sheets", "Sheet1", "--from", "2024-01-08", "--to", "2024-01-10"})
+
+	if code != 0 {
+		t.Fatalf("expected exit code 0, got %d: %s", code, stderr)
+	}
+
+	if lines := strings.Split(strings.TrimSpace(stdout.String()), "\n"); len(lines) != 3 {
+		t.Errorf("expected the shifts of 8 until 10 januari, got %q", stdout)
+	}
+
+	r, _, stderr = newTestRunner(t)
+
+	if code := r.run([]string{"parse", "--file", path, "--name", "Jan", "--sheets", "Maart"}); code == 0 {
+		t.Error("expected an unknown sheet to fail")
+	}
+
+	if !strings.Contains(stderr.String(), "no sheet Maart") {
+		t.Errorf("expected the unknown sheet to be reported, got %q", stderr)
+	}
+}
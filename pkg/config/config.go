@@ -0,0 +1,96 @@
+package config
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+)
+
+// Dir returns the directory in which the user editable configuration of the importer lives. The directory is created
+// when it does not exist yet.
+func Dir() (string, error) {
+	dir, err := os.UserConfigDir()
+
+	if err != nil {
+		return "", err
+	}
+
+	importerdir := filepath.Join(dir, "rooster-importer")
+
+	fi, err := os.Stat(importerdir)
+
+	if err != nil {
+		if err := os.MkdirAll(importerdir, 0775); err != nil {
+			return "", fmt.Errorf("cannot create config directory: %w", err)
+		}
+	} else if !fi.Mode().IsDir() {
+		return "", fmt.Errorf("%s is not a directory", importerdir)
+	}
+
+	return importerdir, nil
+}
+
+// Path returns the location of a configuration file with the given name.
+func Path(name string) (string, error) {
+	dir, err := Dir()
+
+	if err != nil {
+		return "", err
+	}
+
+	return filepath.Join(dir, name), nil
+}
+
+// Load decodes the JSON configuration file with the given name into v. When the file does not exist, the returned
+// error matches os.ErrNotExist.
+func Load(name string, v interface{}) error {
+	path, err := Path(name)
+
+	if err != nil {
+		return err
+	}
+
+	f, err := os.Open(path)
+
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	decoder := json.NewDecoder(f)
+	decoder.DisallowUnknownFields()
+
+	if err := decoder.Decode(v); err != nil {
+		return fmt.Errorf("cannot decode %s: %w", path, err)
+	}
+
+	return nil
+}
+
+// Save writes v as JSON to the configuration file with the given name.
+func Save(name string, v interface{}) error {
+	path, err := Path(name)
+
+	if err != nil {
+		return err
+	}
+
+	contents, err := json.MarshalIndent(v, "", "  ")
+
+	if err != nil {
+		return err
+	}
+
+	if err := os.WriteFile(path, append(contents, '\n'), 0644); err != nil {
+		return fmt.Errorf("cannot save %s: %w", path, err)
+	}
+
+	return nil
+}
+
+// IsNotExist reports whether err was caused by a configuration file that does not exist.
+func IsNotExist(err error) bool {
+	return errors.Is(err, os.ErrNotExist)
+}
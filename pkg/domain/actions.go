@@ -1,97 +1,208 @@
 package domain
 
 import (
+	"bytes"
 	"context"
+	"crypto/sha256"
+	"encoding/hex"
 	"errors"
 	"fmt"
 	"io"
+	"path/filepath"
 	"rooster-importer/pkg/calendar"
 	"rooster-importer/pkg/excelreader"
+	"rooster-importer/pkg/ics"
+	"strings"
 	"time"
 )
 
 type Action func(*Application)
 
-func SelectedXlsxFileAction(file io.ReadCloser, filename string, username string) Action {
+// SelectedXlsxFileAction reads a roster file, and lists the people in it. When the name of the user is known, the
+// roster of the user is converted as well, otherwise that waits until SelectNameAction. The roster is compared with the
+// selected calendar, which stops when ctx is canceled.
+func SelectedXlsxFileAction(ctx context.Context, file io.ReadCloser, filename string, username string) Action {
 	return func(a *Application) {
-		if username == "" {
-			a.guistuff <- errors.New("vul eerst een naam in")
-			return
-		}
-
-		a.xlsxfile = file
 		a.uistate.SelectedXlsxFile = filename
 
 		a.uistate.ConvertedEvents = []*ScheduleEvent{}
 		a.uistate.WarningEvents = []*ScheduleEvent{}
 		a.uistate.FreeDays = []time.Time{}
+		a.uistate.Team = nil
 
 		a.guistuff <- NewState(a.uistate)
 
-		entries, err := excelreader.FindScheduleEntries(file, username)
+		contents, err := io.ReadAll(file)
+		file.Close()
 
 		if err != nil {
-			var noEntriesError *excelreader.NoEntriesFoundError
+			a.guistuff <- fmt.Errorf("cannot read %s: %w", filename, err)
+			return
+		}
 
-			if errors.As(err, &noEntriesError) {
-				// no entry errors will be displayed as info, but won't halt the action
-				a.guistuff <- Information(err.Error())
-			} else {
-				a.guistuff <- err
-				return
-			}
+		hash := sha256.Sum256(contents)
+		a.sourceHash = hex.EncodeToString(hash[:])
+
+		book, err := excelreader.ReadWorkbook(bytes.NewReader(contents), filename)
+
+		if err != nil {
+			a.guistuff <- err
+			return
 		}
 
-		events := []*ScheduleEvent{}
-		free := []time.Time{}
-		warnings := []*ScheduleEvent{}
-		skipped := []time.Time{}
+		a.book = book
+		a.sheets = nil
+		a.uistate.Sheets = book.Sheets()
+		a.uistate.SelectedSheets = book.Sheets()
+		a.username = username
+		a.uistate.SelectedName = username
 
-		for _, entry := range entries {
-			event, conversion := NewScheduleEvent(entry.Shift, entry.Date)
+		a.readEntries(ctx)
 
-			if conversion == ConversionSkipped {
-				// Don't make events for things like empty weekend slots
-				skipped = append(skipped, entry.Date)
-				continue
-			}
+		a.guistuff <- NewState(a.uistate)
+	}
+}
+
+// SelectNameAction converts the roster of the person with the given name in the selected file.
+func SelectNameAction(ctx context.Context, name string) Action {
+	return func(a *Application) {
+		a.username = name
+		a.uistate.SelectedName = name
+
+		a.readEntries(ctx)
+
+		a.guistuff <- NewState(a.uistate)
+	}
+}
+
+// readEntries lists the people in the selected sheets of the file, and finds the roster of the user using the selected
+// layout, which is converted to events.
+func (a *Application) readEntries(ctx context.Context) {
+	if a.book == nil {
+		return
+	}
+
+	a.listNames()
+
+	if a.username == "" {
+		a.entries = nil
+		a.convertEntries()
+		return
+	}
+
+	entries, err := excelreader.FindEntriesInLayout(a.sheetBook(), a.username, a.layout)
+
+	if err != nil {
+		var noEntriesError *excelreader.NoEntriesFoundError
+
+		if errors.As(err, &noEntriesError) {
+			// no entry errors will be displayed as info, but won't halt the action
+			a.guistuff <- Information(err.Error())
+		} else {
+			// an *excelreader.AmbiguousNameError lets the user choose one of the matching people
+			a.guistuff <- err
+			entries = nil
+		}
+	}
+
+	// shifts outside of the selected dates are left out before converting, so they are never imported
+	a.entries = a.inDateRange(entries)
+
+	if err := a.refreshEventsInCalendar(ctx); err != nil {
+		a.guistuff <- err
+	}
+
+	a.convertEntries()
+}
+
+// listNames finds the names of the people in the selected file, in the order of the file.
+func (a *Application) listNames() {
+	a.uistate.Names = []string{}
 
-			events = append(events, event)
+	sheets, err := excelreader.FindNamesInLayout(a.sheetBook(), a.layout)
 
-			switch conversion {
-			case ConversionVrij:
-				free = append(free, entry.Date)
-			case ConversionDefaulted:
-				warnings = append(warnings, event)
+	if err != nil {
+		a.guistuff <- err
+		return
+	}
+
+	seen := make(map[string]bool)
+
+	for _, sheet := range sheets {
+		for _, name := range sheet.Names {
+			if !seen[name] {
+				seen[name] = true
+				a.uistate.Names = append(a.uistate.Names, name)
 			}
 		}
+	}
+}
 
-		a.eventsForCalendar = events
-		a.uistate.ConvertedEvents = events
-		a.uistate.WarningEvents = warnings
-		a.uistate.FreeDays = free
-		a.uistate.SkippedDays = skipped
+// convertEntries converts the entries read from the Excel file to events using the current mapping table.
+func (a *Application) convertEntries() {
+	converted := a.mapping.convert(a.entries)
 
-		a.DeduplicateEvents()
+	a.eventsForCalendar = converted.events
+	a.uistate.ConvertedEvents = converted.events
+	a.uistate.WarningEvents = converted.warnings
+	a.uistate.FreeDays = converted.free
+	a.uistate.SkippedDays = converted.skipped
 
-		a.guistuff <- NewState(a.uistate)
+	a.DeduplicateEvents()
+}
+
+// convertedRoster holds the events of a roster, and the days for which no events were made.
+type convertedRoster struct {
+	events   []*ScheduleEvent
+	warnings []*ScheduleEvent
+	free     []time.Time
+	skipped  []time.Time
+}
+
+// convert converts the entries of a roster to events using the mapping table.
+func (m *ShiftMapping) convert(entries []excelreader.ScheduleEntry) convertedRoster {
+	converted := convertedRoster{
+		events:   []*ScheduleEvent{},
+		warnings: []*ScheduleEvent{},
+		free:     []time.Time{},
+		skipped:  []time.Time{},
+	}
+
+	for _, entry := range entries {
+		event, conversion := m.NewScheduleEvent(entry.Shift, entry.Date)
+
+		if conversion == ConversionSkipped {
+			// Don't make events for things like empty weekend slots
+			converted.skipped = append(converted.skipped, entry.Date)
+			continue
+		}
+
+		converted.events = append(converted.events, event)
+
+		switch conversion {
+		case ConversionVrij:
+			converted.free = append(converted.free, entry.Date)
+		case ConversionDefaulted:
+			converted.warnings = append(converted.warnings, event)
+		}
 	}
+
+	return converted
 }
 
-func SelectCalendarAction(calendarName string) Action {
+// SelectCalendarAction imports into the calendar with the given name, and compares the roster with the events in it.
+func SelectCalendarAction(ctx context.Context, calendarName string) Action {
 	return func(a *Application) {
 		a.selectedCalendarName = calendarName
 
-		client, err := calendar.LogIn()
+		client, err := a.provider.LogIn()
 
 		if err != nil {
-			a.guistuff <- fmt.Errorf("cannot log into google calendar: %w", err)
+			a.guistuff <- fmt.Errorf("cannot log into %s: %w", a.provider.Name(), err)
 			return
 		}
 
-		ctx := context.Background()
-
-		calendarId, err := client.FindCalendarIdByName(ctx, a.selectedCalendarName)
+		calendarId, err := calendar.FindCalendarIdByName(ctx, client, a.selectedCalendarName)
 
 		if err != nil {
 			a.guistuff <- fmt.Errorf("cannot find calendar ID for calendar %s: %w", a.selectedCalendarName, err)
@@ -102,16 +213,8 @@ func SelectCalendarAction(calendarName string) Action {
 		a.uistate.SelectedCalendarName = calendarName
 		a.guistuff <- NewState(a.uistate)
 
-		events, err := client.ListEvents(ctx, calendarId)
-
-		if err != nil {
-			a.guistuff <- fmt.Errorf("couldn't get existing events in calendar: %w", err)
-		}
-
-		a.eventsInCalendar = make([]*ScheduleEvent, len(events))
-
-		for i, e := range events {
-			a.eventsInCalendar[i] = calendarToScheduleEvent(&e)
+		if err := a.refreshEventsInCalendar(ctx); err != nil {
+			a.guistuff <- err
 		}
 
 		a.DeduplicateEvents()
@@ -120,11 +223,44 @@ func SelectCalendarAction(calendarName string) Action {
 	}
 }
 
-func ClickedCalendarLoginAction() Action {
-	return func(a *Application) {
-		fmt.Println("Login Action")
+// refreshEventsInCalendar fetches the events in the selected calendar on the dates of the roster. Without a roster there
+// is nothing to compare the calendar with, so nothing is fetched. Events that the calendar cannot convert are reported,
+// but don't stop the others from being compared.
+func (a *Application) refreshEventsInCalendar(ctx context.Context) error {
+	a.eventsInCalendar = []*ScheduleEvent{}
 
-		calendars, err := listCalendars()
+	if len(a.entries) == 0 || a.selectedCalendarId == "" {
+		return nil
+	}
+
+	client, err := a.provider.LogIn()
+
+	if err != nil {
+		return fmt.Errorf("cannot log into %s: %w", a.provider.Name(), err)
+	}
+
+	// a day extra on both sides, so that shifts are found whatever the time zone of the calendar is
+	from, to := a.rosterRange()
+	events, err := client.ListEvents(ctx, a.selectedCalendarId, from.AddDate(0, 0, -1), to.AddDate(0, 0, 1))
+
+	var skipped *calendar.SkippedEventsError
+
+	if errors.As(err, &skipped) {
+		a.guistuff <- Information(err.Error())
+	} else if err != nil {
+		return fmt.Errorf("couldn't get existing events in calendar: %w", err)
+	}
+
+	for _, e := range events {
+		a.eventsInCalendar = append(a.eventsInCalendar, calendarToScheduleEvent(&e))
+	}
+
+	return nil
+}
+
+func ClickedCalendarLoginAction(ctx context.Context) Action {
+	return func(a *Application) {
+		calendars, err := a.listCalendars(ctx)
 
 		if err != nil {
 			a.guistuff <- err
@@ -132,8 +268,6 @@ func ClickedCalendarLoginAction() Action {
 			a.uistate.IsLoggedIn = true
 			a.uistate.AvailableCalendars = make([]string, len(calendars))
 
-			fmt.Println(calendars)
-
 			for i, cal := range calendars {
 				a.uistate.AvailableCalendars[i] = cal.Name
 			}
@@ -144,7 +278,7 @@ func ClickedCalendarLoginAction() Action {
 
 func ClickedCalendarLogoutAction() Action {
 	return func(a *Application) {
-		err := calendar.LogOut()
+		err := a.provider.LogOut()
 
 		if err != nil {
 			a.guistuff <- err
@@ -156,14 +290,14 @@ func ClickedCalendarLogoutAction() Action {
 	}
 }
 
-func listCalendars() ([]calendar.CalendarItem, error) {
-	client, err := calendar.LogIn()
+func (a *Application) listCalendars(ctx context.Context) ([]calendar.CalendarItem, error) {
+	client, err := a.provider.LogIn()
 
 	if err != nil {
-		return nil, fmt.Errorf("cannot login to google calendar: %w", err)
+		return nil, fmt.Errorf("cannot login to %s: %w", a.provider.Name(), err)
 	}
 
-	calendars, err := client.ListCalendars(context.TODO())
+	calendars, err := client.ListCalendars(ctx)
 
 	if err != nil {
 		return nil, fmt.Errorf("cannot list calendars: %w", err)
@@ -172,79 +306,434 @@ func listCalendars() ([]calendar.CalendarItem, error) {
 	return calendars, nil
 }
 
-func GuiAttachedAction() Action {
+// LoadShiftMappingAction reads the mapping table from the config directory. Invalid tables are reported, after which
+// the built in mapping table is used.
+func LoadShiftMappingAction() Action {
 	return func(a *Application) {
-		// When the GUI attaches, determine if user is logged into google cal
-		a.uistate.IsLoggedIn = calendar.IsLoggedIn()
+		a.loadShiftMapping()
+	}
+}
 
-		// If a user is already logged in, fetch calendars and show those too
-		if a.uistate.IsLoggedIn {
-			calendars, err := listCalendars()
+func (a *Application) loadShiftMapping() {
+	mapping, err := LoadShiftMapping()
 
-			if err != nil {
-				a.guistuff <- err
-			} else {
-				a.uistate.AvailableCalendars = make([]string, len(calendars))
+	if err != nil {
+		a.mapping = DefaultShiftMapping()
+		a.uistate.Shifts = a.mapping.Shifts
+		a.guistuff <- fmt.Errorf("cannot load shift mapping, using the built in mapping instead: %w", err)
+		return
+	}
 
-				for i, cal := range calendars {
-					a.uistate.AvailableCalendars[i] = cal.Name
-				}
-			}
+	a.mapping = mapping
+	a.uistate.Shifts = a.mapping.Shifts
+}
+
+// SaveShiftMappingAction replaces the shifts in the mapping table, saves the table to the config directory, and
+// converts the selected Excel file again so that the preview reflects the new table.
+func SaveShiftMappingAction(shifts []ShiftDefinition) Action {
+	return func(a *Application) {
+		mapping := &ShiftMapping{
+			Shifts:  shifts,
+			Default: a.mapping.Default,
+		}
+
+		if err := mapping.Save(); err != nil {
+			a.guistuff <- fmt.Errorf("cannot save shift mapping: %w", err)
+			return
 		}
+
+		a.mapping = mapping
+		a.uistate.Shifts = mapping.Shifts
+
+		a.convertEntries()
+
+		a.guistuff <- NewState(a.uistate)
+	}
+}
+
+func GuiAttachedAction(ctx context.Context) Action {
+	return func(a *Application) {
+		a.loadShiftMapping()
+		a.loadLayouts()
+		a.refreshLoginState(ctx)
+		a.loadLastImport()
+
 		a.guistuff <- NewState(a.uistate)
 	}
 }
 
-func scheduleToCalendarEvent(sched *ScheduleEvent) calendar.CalendarEvent {
+// refreshLoginState determines if the user is logged into the calendar, and if so, fetches the calendars as well
+func (a *Application) refreshLoginState(ctx context.Context) {
+	a.uistate.CalendarProvider = a.provider.Name()
+	a.uistate.IsLoggedIn = a.provider.IsLoggedIn()
+	a.uistate.AvailableCalendars = []string{}
+
+	if a.uistate.IsLoggedIn {
+		calendars, err := a.listCalendars(ctx)
+
+		if err != nil {
+			a.guistuff <- err
+		} else {
+			a.uistate.AvailableCalendars = make([]string, len(calendars))
+
+			for i, cal := range calendars {
+				a.uistate.AvailableCalendars[i] = cal.Name
+			}
+		}
+	}
+}
+
+func (a *Application) useProvider(ctx context.Context, provider calendar.Provider) {
+	a.provider = provider
+	a.selectedCalendarName = ""
+	a.selectedCalendarId = ""
+	a.eventsInCalendar = []*ScheduleEvent{}
+	a.uistate.SelectedCalendarName = ""
+
+	a.refreshLoginState(ctx)
+	a.DeduplicateEvents()
+
+	a.guistuff <- NewState(a.uistate)
+}
+
+func UseGoogleCalendarAction(ctx context.Context) Action {
+	return func(a *Application) {
+		a.useProvider(ctx, calendar.GoogleProvider{})
+	}
+}
+
+// UseCalDAVAction switches to a CalDAV server. Without a URL, the login that was stored on a previous login is used.
+func UseCalDAVAction(ctx context.Context, url, username, password string) Action {
+	return func(a *Application) {
+		provider := &calendar.CalDAVProvider{}
+
+		if url != "" {
+			provider.Credentials = &calendar.CalDAVCredentials{
+				URL:      url,
+				Username: username,
+				Password: password,
+			}
+		}
+
+		a.useProvider(ctx, provider)
+	}
+}
+
+// scheduleToCalendarEvent converts an event from the roster, and tags it with the roster cell and file it came from.
+func scheduleToCalendarEvent(sched *ScheduleEvent, sourceHash string) calendar.CalendarEvent {
 	return calendar.CalendarEvent{
+		Id:     sched.Id,
 		Title:  sched.ScheduleType,
 		Start:  sched.Start,
 		End:    sched.End,
 		AllDay: sched.AllDay,
+		Identity: &calendar.Identity{
+			Importer:   calendar.ImporterId,
+			RosterDate: sched.RosterDate.Format(time.DateOnly),
+			ShiftCode:  sched.Code,
+			SourceHash: sourceHash,
+		},
 	}
 }
 
 func calendarToScheduleEvent(cal *calendar.CalendarEvent) *ScheduleEvent {
-	return &ScheduleEvent{
+	event := &ScheduleEvent{
 		ScheduleType: cal.Title,
 		Start:        cal.Start,
 		End:          cal.End,
 		AllDay:       cal.AllDay,
+		Id:           cal.Id,
 	}
+
+	if cal.Identity != nil && cal.Identity.Importer == calendar.ImporterId {
+		event.Managed = true
+		event.Code = cal.Identity.ShiftCode
+
+		// events with a damaged roster date are still managed, but fall back to their start date
+		if date, err := time.Parse(time.DateOnly, cal.Identity.RosterDate); err == nil {
+			event.RosterDate = date
+		}
+	}
+
+	return event
 }
 
-func ImportEntriesToCalendar() Action {
+// SetSyncModeAction chooses between only adding new events, or also updating and deleting the events that were
+// imported before.
+func SetSyncModeAction(enabled bool) Action {
 	return func(a *Application) {
+		a.uistate.SyncMode = enabled
+
+		a.DeduplicateEvents()
 
-		client, err := calendar.LogIn()
+		a.guistuff <- NewState(a.uistate)
+	}
+}
+
+func scheduleToIcsEvent(sched *ScheduleEvent, person string) ics.Event {
+	return ics.Event{
+		UID:    sched.PersonUID(person),
+		Title:  sched.ScheduleType,
+		Start:  sched.Start,
+		End:    sched.End,
+		AllDay: sched.AllDay,
+	}
+}
+
+// ExportIcsAction writes all events converted from the selected Excel file to an iCalendar file.
+func ExportIcsAction(file io.WriteCloser, filename string) Action {
+	return func(a *Application) {
+		if len(a.eventsForCalendar) == 0 {
+			file.Close()
+			a.guistuff <- errors.New("no events to export, select a roster first")
+			return
+		}
+
+		if err := writeIcs(file, a.eventsForCalendar, ""); err != nil {
+			a.guistuff <- fmt.Errorf("cannot write %s: %w", filename, err)
+			return
+		}
+
+		a.guistuff <- Information(fmt.Sprintf("Saved %d events to %s", len(a.eventsForCalendar), filename))
+	}
+}
+
+// writeIcs writes the events of a person to an iCalendar file, and closes the file. The person is empty for the roster
+// of the user.
+func writeIcs(file io.WriteCloser, scheduleEvents []*ScheduleEvent, person string) error {
+	events := make([]ics.Event, len(scheduleEvents))
+
+	for i, event := range scheduleEvents {
+		events[i] = scheduleToIcsEvent(event, person)
+	}
+
+	err := ics.Write(file, events)
+
+	if closeErr := file.Close(); err == nil {
+		err = closeErr
+	}
+
+	return err
+}
+
+// ImportEntriesToCalendar creates, updates and deletes events in the selected calendar. When ctx is canceled, the import
+// stops after the change that is being written, and reports which changes were written before that.
+func ImportEntriesToCalendar(ctx context.Context) Action {
+	return func(a *Application) {
+
+		client, err := a.provider.LogIn()
 
 		if err != nil {
-			a.guistuff <- fmt.Errorf("cannot log into google calendar: %w", err)
+			a.guistuff <- fmt.Errorf("cannot log into %s: %w", a.provider.Name(), err)
 			return
 		}
 
-		ctx := context.Background()
+		record := ImportRecord{
+			File:         a.uistate.SelectedXlsxFile,
+			Provider:     a.provider.Name(),
+			CalendarId:   a.selectedCalendarId,
+			CalendarName: a.selectedCalendarName,
+			Time:         time.Now(),
+		}
+
+		changes := []change{}
+
+		for _, event := range a.newEventsForCalendar {
+			calEvent := scheduleToCalendarEvent(event, a.sourceHash)
+
+			changes = append(changes, change{kind: ChangeCreate, event: event, write: func(ctx context.Context) (string, error) {
+				// the id is chosen here, so that an event that is created while the import is canceled can be undone
+				if calEvent.Id == "" {
+					id, err := calendar.NewEventId()
+
+					if err != nil {
+						return "", err
+					}
+
+					calEvent.Id = id
+				}
+
+				created, err := client.CreateEvent(ctx, record.CalendarId, &calEvent)
+
+				if err != nil {
+					return calEvent.Id, err
+				}
+
+				return created.Id, nil
+			}})
+		}
+
+		for _, event := range a.eventsToUpdate {
+			calEvent := scheduleToCalendarEvent(event, a.sourceHash)
+
+			changes = append(changes, change{kind: ChangeUpdate, event: event, write: func(ctx context.Context) (string, error) {
+				_, err := client.UpdateEvent(ctx, record.CalendarId, &calEvent)
+				return calEvent.Id, err
+			}})
+		}
+
+		for _, event := range a.eventsToDelete {
+			id := event.Id
+
+			changes = append(changes, change{kind: ChangeDelete, event: event, write: func(ctx context.Context) (string, error) {
+				return id, client.DeleteEvent(ctx, record.CalendarId, id)
+			}})
+		}
+
+		total := len(changes)
+		outcomes, canceled := a.writeChanges(ctx, changes)
+		results := make([]EventResult, len(outcomes))
+
+		for i, result := range outcomes {
+			results[i] = result.EventResult
+
+			if result.Change == ChangeCreate && (result.Err == nil || result.uncertain) {
+				record.Events = append(record.Events, JournalEvent{Id: result.id, Summary: result.Event.Summary()})
+			}
+		}
+
+		a.guistuff <- Progress{Done: len(results), Total: total, Canceled: canceled, Finished: true, Results: results}
 
-		errors := []error{}
+		if err := a.recordImport(record); err != nil {
+			a.guistuff <- fmt.Errorf("cannot save the import journal, so this import cannot be undone: %w", err)
+		}
 
-		for i, event := range a.newEventsForCalendar {
-			calEvent := scheduleToCalendarEvent(event)
+		// compare with the calendar again, so that importing once more only writes what is still missing. A canceled
+		// ctx cannot list the calendar anymore, so then the changes that were written are applied instead.
+		if canceled {
+			a.applyWrittenChanges(outcomes)
+		} else if err := a.refreshEventsInCalendar(ctx); err != nil {
+			a.guistuff <- err
+		}
 
-			_, err := client.CreateEvent(ctx, a.selectedCalendarId, &calEvent)
+		a.DeduplicateEvents()
+		a.guistuff <- NewState(a.uistate)
 
-			if err != nil {
-				fmt.Printf("error in calendar event create: %s\n", err.Error())
-				errors = append(errors, err)
+		written := map[ChangeKind][]*ScheduleEvent{}
+		failures := []EventResult{}
+		unknown := []*ScheduleEvent{}
+
+		for _, result := range outcomes {
+			if result.uncertain {
+				unknown = append(unknown, result.Event)
+			} else if result.Err != nil {
+				failures = append(failures, result.EventResult)
+			} else {
+				written[result.Change] = append(written[result.Change], result.Event)
 			}
+		}
+
+		created, updated, deleted := written[ChangeCreate], written[ChangeUpdate], written[ChangeDelete]
 
-			a.guistuff <- Progress{Done: i + 1, Total: len(a.newEventsForCalendar)}
+		if canceled {
+			a.guistuff <- &ImportCanceledError{Created: created, Updated: updated, Deleted: deleted, Unknown: unknown}
+			return
+		}
+
+		if len(failures) != 0 {
+			a.guistuff <- fmt.Errorf("%d of %d changes failed, 1st error: %s %s: %w", len(failures), total, failures[0].Change, failures[0].Event.Summary(), failures[0].Err)
+			return
+		}
+
+		if len(updated)+len(deleted) > 0 {
+			a.guistuff <- Information(fmt.Sprintf("Successfully imported %d events, updated %d events and deleted %d events", len(created), len(updated), len(deleted)))
+			return
+		}
+
+		a.guistuff <- Information(fmt.Sprintf("Successfully imported %d events", len(created)))
+	}
+}
+
+// UndoLastImportAction deletes the events that were created by the last import in the journal. Events that could not be
+// deleted stay in the journal, so that undoing can be tried again.
+func UndoLastImportAction(ctx context.Context) Action {
+	return func(a *Application) {
+		journal, err := loadJournal()
+
+		if err != nil {
+			a.guistuff <- fmt.Errorf("cannot read the import journal: %w", err)
+			return
+		}
+
+		record := journal.last()
+
+		if record == nil {
+			a.guistuff <- errors.New("there is no import to undo")
+			return
 		}
 
-		if len(errors) != 0 {
-			a.guistuff <- fmt.Errorf("%d errors occured: 1st error: %w", len(errors), errors[0])
+		if record.Provider != a.provider.Name() {
+			a.guistuff <- fmt.Errorf("the last import was into %s, switch to %s to undo it", record.Provider, record.Provider)
 			return
 		}
 
-		a.guistuff <- Information(fmt.Sprintf("Successfully imported %d events", len(a.newEventsForCalendar)))
+		client, err := a.provider.LogIn()
+
+		if err != nil {
+			a.guistuff <- fmt.Errorf("cannot log into %s: %w", a.provider.Name(), err)
+			return
+		}
+
+		removed := []string{}
+		removedIds := []string{}
+		remaining := []JournalEvent{}
+		failures := []error{}
+
+		total := len(record.Events)
+		canceled := false
+
+		for i, event := range record.Events {
+			if ctx.Err() != nil {
+				remaining = append(remaining, record.Events[i:]...)
+				canceled = true
+				break
+			}
+
+			// an event that is gone was deleted already, or never created by an import that was canceled
+			if err := client.DeleteEvent(ctx, record.CalendarId, event.Id); err != nil && !calendar.IsGone(err) {
+				failures = append(failures, err)
+				remaining = append(remaining, event)
+			} else {
+				removed = append(removed, event.Summary)
+				removedIds = append(removedIds, event.Id)
+			}
+
+			a.guistuff <- Progress{Done: i + 1, Total: total}
+		}
+
+		a.guistuff <- Progress{Done: total - len(remaining) + len(failures), Total: total, Finished: true, Canceled: canceled}
+
+		description := fmt.Sprintf("%s in %s", filepath.Base(record.File), record.CalendarName)
+
+		if len(remaining) == 0 {
+			journal.Imports = journal.Imports[:len(journal.Imports)-1]
+		} else {
+			record.Events = remaining
+		}
+
+		if err := journal.save(); err != nil {
+			a.guistuff <- err
+		}
+
+		a.loadLastImport()
+
+		if canceled {
+			a.forgetEventsInCalendar(removedIds)
+		} else if err := a.refreshEventsInCalendar(ctx); err != nil {
+			a.guistuff <- err
+		}
+
+		a.DeduplicateEvents()
+		a.guistuff <- NewState(a.uistate)
+
+		if len(removed) > 0 {
+			a.guistuff <- Information(fmt.Sprintf("Removed %d events of the import of %s:\n%s", len(removed), description, strings.Join(removed, "\n")))
+		}
+
+		if len(failures) > 0 {
+			a.guistuff <- fmt.Errorf("%d events could not be removed: 1st error: %w", len(failures), failures[0])
+		} else if len(remaining) > 0 {
+			a.guistuff <- fmt.Errorf("undo canceled, %d events of the import of %s were not removed", len(remaining), description)
+		}
 	}
 }
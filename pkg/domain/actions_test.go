@@ -0,0 +1,1024 @@
+package domain_test
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"rooster-importer/pkg/calendar"
+	"rooster-importer/pkg/calendar/calendartest"
+	"rooster-importer/pkg/domain"
+	"rooster-importer/pkg/excelreader"
+	"rooster-importer/pkg/ics"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/xuri/excelize/v2"
+)
+
+// testApp runs actions on an application, and keeps the messages it sends to the UI.
+type testApp struct {
+	t        *testing.T
+	app      *domain.Application
+	backend  *calendartest.Backend
+	state    domain.UIState
+	errors   []error
+	messages []interface{}
+}
+
+func newTestApp(t *testing.T) *testApp {
+	t.Setenv("XDG_CONFIG_HOME", t.TempDir())
+	t.Setenv("XDG_CACHE_HOME", t.TempDir())
+	t.Setenv("HOME", t.TempDir())
+
+	backend := calendartest.NewBackend("Werk", "Prive")
+
+	return &testApp{
+		t:       t,
+		app:     domain.NewApplication(&calendartest.Provider{Backend: backend, LoggedIn: true}),
+		backend: backend,
+	}
+}
+
+func (ta *testApp) dispatch(action domain.Action) {
+	done := make(chan struct{})
+
+	go func() {
+		action(ta.app)
+		close(done)
+	}()
+
+	for {
+		select {
+		case msg := <-ta.app.GuiStuff():
+			ta.messages = append(ta.messages, msg)
+
+			switch m := msg.(type) {
+			case error:
+				ta.errors = append(ta.errors, m)
+			case domain.NewState:
+				ta.state = domain.UIState(m)
+			}
+		case <-done:
+			return
+		}
+	}
+}
+
+func (ta *testApp) selectRoster(shifts string) {
+	ta.t.Helper()
+	ta.selectRosterFor(shifts, "Jan")
+}
+
+// selectRosterFor selects a roster of Jan de Vries, in which the roster of username is read
+func (ta *testApp) selectRosterFor(shifts string, username string) {
+	ta.t.Helper()
+
+	file := excelize.NewFile()
+	sheet := file.GetSheetName(0)
+
+	file.SetCellValue(sheet, "A2", "Jan de Vries")
+
+	for i, shift := range strings.Split(shifts, " ") {
+		datecell, _ := excelize.CoordinatesToCellName(i+2, 1)
+		shiftcell, _ := excelize.CoordinatesToCellName(i+2, 2)
+
+		file.SetCellValue(sheet, datecell, fmt.Sprintf("2024-1-%d", i+1))
+		file.SetCellValue(sheet, shiftcell, shift)
+	}
+
+	path := filepath.Join(ta.t.TempDir(), "rooster.xlsx")
+
+	if err := file.SaveAs(path); err != nil {
+		ta.t.Fatal(err)
+	}
+
+	f, err := os.Open(path)
+
+	if err != nil {
+		ta.t.Fatal(err)
+	}
+
+	ta.dispatch(domain.SelectedXlsxFileAction(context.Background(), f, path, username))
+}
+
+func (ta *testApp) checkNoErrors() {
+	ta.t.Helper()
+
+	for _, err := range ta.errors {
+		ta.t.Error(err)
+	}
+}
+
+func TestImportSkipsExistingEvents(t *testing.T) {
+	ta := newTestApp(t)
+
+	// the first shift of the roster is already in the calendar
+	_, err := ta.backend.CreateEvent(context.Background(), "calendar-1", &calendar.CalendarEvent{
+		Title: "Dag",
+		Start: time.Date(2024, 1, 1, 7, 45, 0, 0, time.Local),
+		End:   time.Date(2024, 1, 1, 16, 15, 0, 0, time.Local),
+	})
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ta.dispatch(domain.GuiAttachedAction(context.Background()))
+	ta.selectRoster("d t a n x x x d d d d d x x")
+	ta.dispatch(domain.SelectCalendarAction(context.Background(), "Werk"))
+
+	if len(ta.state.ConvertedEvents) != 10 {
+		t.Errorf("expected 10 converted events, got %d", len(ta.state.ConvertedEvents))
+	}
+
+	if len(ta.state.EventsNotAlreadyInCalendar) != 9 {
+		t.Errorf("expected 9 new events, got %d", len(ta.state.EventsNotAlreadyInCalendar))
+	}
+
+	ta.dispatch(domain.ImportEntriesToCalendar(context.Background()))
+	ta.checkNoErrors()
+
+	if events := ta.backend.Events("calendar-1"); len(events) != 10 {
+		t.Errorf("expected 10 events in the calendar after importing, got %d", len(events))
+	}
+
+	if events := ta.backend.Events("calendar-2"); len(events) != 0 {
+		t.Errorf("expected no events in the other calendar, got %d", len(events))
+	}
+}
+
+func TestSelectUnknownCalendar(t *testing.T) {
+	ta := newTestApp(t)
+
+	ta.dispatch(domain.SelectCalendarAction(context.Background(), "Werk (oud)"))
+
+	if len(ta.errors) != 1 {
+		t.Errorf("expected an error for an unknown calendar, got %v", ta.errors)
+	}
+}
+
+func TestSelectCalendarCanceled(t *testing.T) {
+	ta := newTestApp(t)
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	ta.selectRoster("d t a n x x x d d d d d x x")
+	ta.dispatch(domain.SelectCalendarAction(ctx, "Werk"))
+
+	if len(ta.errors) != 1 || !errors.Is(ta.errors[0], context.Canceled) {
+		t.Errorf("expected the calendar not to be read with a canceled context, got %v", ta.errors)
+	}
+}
+
+func TestSynchronize(t *testing.T) {
+	ta := newTestApp(t)
+
+	// a personal event on the first day that should never be touched
+	_, err := ta.backend.CreateEvent(context.Background(), "calendar-1", &calendar.CalendarEvent{
+		Title: "Tandarts",
+		Start: time.Date(2024, 1, 1, 9, 0, 0, 0, time.Local),
+		End:   time.Date(2024, 1, 1, 9, 30, 0, 0, time.Local),
+	})
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ta.dispatch(domain.GuiAttachedAction(context.Background()))
+	ta.dispatch(domain.SetSyncModeAction(true))
+	ta.selectRoster("d t a n x d x d d d d d x x")
+	ta.dispatch(domain.SelectCalendarAction(context.Background(), "Werk"))
+	ta.dispatch(domain.ImportEntriesToCalendar(context.Background()))
+	ta.checkNoErrors()
+
+	if events := ta.backend.Events("calendar-1"); len(events) != 12 {
+		t.Fatalf("expected 12 events after the first import, got %d", len(events))
+	}
+
+	// the roster changes: the first day becomes an evening shift, the second day is free, and the saturday is
+	// no longer worked
+	ta.selectRoster("a x a n x x x d d d d d x x")
+	ta.dispatch(domain.SelectCalendarAction(context.Background(), "Werk"))
+
+	if len(ta.state.EventsNotAlreadyInCalendar) != 0 || len(ta.state.EventsToUpdate) != 2 || len(ta.state.EventsToDelete) != 1 {
+		t.Fatalf("expected 0 new, 2 changed and 1 removed events, got %d, %d and %d",
+			len(ta.state.EventsNotAlreadyInCalendar), len(ta.state.EventsToUpdate), len(ta.state.EventsToDelete))
+	}
+
+	ta.dispatch(domain.ImportEntriesToCalendar(context.Background()))
+	ta.checkNoErrors()
+
+	titles := []string{}
+
+	for _, event := range ta.backend.Events("calendar-1") {
+		if event.Start.Day() <= 2 {
+			titles = append(titles, event.Title)
+		}
+	}
+
+	if strings.Join(titles, ",") != "Tandarts,Avond,Vrij" {
+		t.Errorf("unexpected events on the first two days: %v", titles)
+	}
+
+	if events := ta.backend.Events("calendar-1"); len(events) != 11 {
+		t.Errorf("expected 11 events after synchronizing, got %d", len(events))
+	}
+}
+
+func TestRecognizeImportedEvents(t *testing.T) {
+	ta := newTestApp(t)
+
+	ta.dispatch(domain.GuiAttachedAction(context.Background()))
+	ta.selectRoster("d t a n x x x d d d d d x x")
+	ta.dispatch(domain.SelectCalendarAction(context.Background(), "Werk"))
+	ta.dispatch(domain.ImportEntriesToCalendar(context.Background()))
+	ta.checkNoErrors()
+
+	events := ta.backend.Events("calendar-1")
+
+	if identity := events[0].Identity; identity == nil || identity.RosterDate != "2024-01-01" || identity.ShiftCode != "d" || len(identity.SourceHash) != 64 {
+		t.Fatalf("expected the first event to be tagged with its roster cell, got %+v", identity)
+	}
+
+	// the user renames the first shift, and the calendar returns the second shift in another time zone
+	renamed := events[0]
+	renamed.Title = "Dag (geruild)"
+
+	zone := time.FixedZone("UTC+5", 5*60*60)
+	moved := events[1]
+	moved.Start = moved.Start.In(zone)
+	moved.End = moved.End.In(zone)
+
+	for _, event := range []calendar.CalendarEvent{renamed, moved} {
+		if _, err := ta.backend.UpdateEvent(context.Background(), "calendar-1", &event); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// a personal event with the same title as a shift, on a day without that shift
+	_, err := ta.backend.CreateEvent(context.Background(), "calendar-2", &calendar.CalendarEvent{
+		Title: "Dag",
+		Start: time.Date(2024, 1, 2, 7, 45, 0, 0, time.Local),
+		End:   time.Date(2024, 1, 2, 16, 15, 0, 0, time.Local),
+	})
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ta.selectRoster("d t a n x x x d d d d d x x")
+	ta.dispatch(domain.SelectCalendarAction(context.Background(), "Werk"))
+
+	if len(ta.state.EventsNotAlreadyInCalendar) != 0 {
+		t.Errorf("expected all events to be recognized, got %d new events", len(ta.state.EventsNotAlreadyInCalendar))
+	}
+
+	ta.dispatch(domain.SelectCalendarAction(context.Background(), "Prive"))
+
+	if len(ta.state.EventsNotAlreadyInCalendar) != 10 {
+		t.Errorf("expected the personal event not to be mistaken for a shift, got %d new events", len(ta.state.EventsNotAlreadyInCalendar))
+	}
+}
+
+func TestCalendarEventsWithinRoster(t *testing.T) {
+	ta := newTestApp(t)
+
+	// the same shift a year earlier is outside of the roster, and should not be fetched
+	for _, year := range []int{2023, 2024} {
+		_, err := ta.backend.CreateEvent(context.Background(), "calendar-1", &calendar.CalendarEvent{
+			Title: "Dag",
+			Start: time.Date(year, 1, 1, 7, 45, 0, 0, time.Local),
+			End:   time.Date(year, 1, 1, 16, 15, 0, 0, time.Local),
+		})
+
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// the calendar is chosen before the roster, so the events are fetched once the dates of the roster are known
+	ta.dispatch(domain.GuiAttachedAction(context.Background()))
+	ta.dispatch(domain.SelectCalendarAction(context.Background(), "Werk"))
+	ta.selectRoster("d t a n x x x d d d d d x x")
+	ta.checkNoErrors()
+
+	if len(ta.state.EventsNotAlreadyInCalendar) != 9 {
+		t.Errorf("expected 9 new events, got %d", len(ta.state.EventsNotAlreadyInCalendar))
+	}
+}
+
+// cancelingBackend cancels the import once a number of events were created. With lost, the last of those is created
+// but the cancel happens before its response arrives.
+type cancelingBackend struct {
+	*calendartest.Backend
+	cancel context.CancelFunc
+	after  int
+	lost   bool
+}
+
+func (b *cancelingBackend) CreateEvent(ctx context.Context, calendarId string, event *calendar.CalendarEvent) (*calendar.CalendarEvent, error) {
+	created, err := b.Backend.CreateEvent(ctx, calendarId, event)
+
+	if b.after -= 1; b.after == 0 {
+		b.cancel()
+
+		if b.lost {
+			return nil, ctx.Err()
+		}
+	}
+
+	return created, err
+}
+
+func TestCancelImport(t *testing.T) {
+	ta := newTestApp(t)
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	ta.app = domain.NewApplication(&calendartest.Provider{
+		Backend:  &cancelingBackend{Backend: ta.backend, cancel: cancel, after: 3},
+		LoggedIn: true,
+	})
+
+	// one at a time, so that it is known which events were created before canceling
+	ta.dispatch(domain.SetConcurrencyAction(1))
+	ta.dispatch(domain.GuiAttachedAction(context.Background()))
+	ta.selectRoster("d t a n x x x d d d d d x x")
+	ta.dispatch(domain.SelectCalendarAction(context.Background(), "Werk"))
+	ta.dispatch(domain.ImportEntriesToCalendar(ctx))
+
+	var canceled *domain.ImportCanceledError
+
+	if len(ta.errors) != 1 || !errors.As(ta.errors[0], &canceled) {
+		t.Fatalf("expected the import to be canceled, got %v", ta.errors)
+	}
+
+	if len(canceled.Created) != 3 || canceled.Created[2].ScheduleType != "Avond" {
+		t.Errorf("expected the first 3 events to be reported as created, got %s", canceled)
+	}
+
+	if events := ta.backend.Events("calendar-1"); len(events) != 3 {
+		t.Errorf("expected 3 events in the calendar, got %d", len(events))
+	}
+
+	// the events that were created are not created again when importing the rest
+	if len(ta.state.EventsNotAlreadyInCalendar) != 7 {
+		t.Errorf("expected 7 events left to import, got %d", len(ta.state.EventsNotAlreadyInCalendar))
+	}
+}
+
+func TestCancelImportWhileCreating(t *testing.T) {
+	ta := newTestApp(t)
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	ta.app = domain.NewApplication(&calendartest.Provider{
+		Backend:  &cancelingBackend{Backend: ta.backend, cancel: cancel, after: 3, lost: true},
+		LoggedIn: true,
+	})
+
+	ta.dispatch(domain.SetConcurrencyAction(1))
+	ta.dispatch(domain.GuiAttachedAction(context.Background()))
+	ta.selectRoster("d t a n x x x d d d d d x x")
+	ta.dispatch(domain.SelectCalendarAction(context.Background(), "Werk"))
+	ta.dispatch(domain.ImportEntriesToCalendar(ctx))
+
+	var canceled *domain.ImportCanceledError
+
+	if len(ta.errors) != 1 || !errors.As(ta.errors[0], &canceled) {
+		t.Fatalf("expected the import to be canceled, got %v", ta.errors)
+	}
+
+	// the server created the 3rd event, but the importer can't know that
+	if len(canceled.Created) != 2 || len(canceled.Unknown) != 1 || canceled.Unknown[0].ScheduleType != "Avond" {
+		t.Errorf("expected 2 events to be created and 1 to be unknown, got %s", canceled)
+	}
+
+	if events := ta.backend.Events("calendar-1"); len(events) != 3 {
+		t.Fatalf("expected 3 events in the calendar, got %d", len(events))
+	}
+
+	// undoing removes the event that may exist as well
+	ta.errors = nil
+	ta.dispatch(domain.UndoLastImportAction(context.Background()))
+	ta.checkNoErrors()
+
+	if events := ta.backend.Events("calendar-1"); len(events) != 0 {
+		t.Errorf("expected the undo to remove all events, got %+v", events)
+	}
+}
+
+func TestUndoImportOfEventsThatAreGone(t *testing.T) {
+	ta := newTestApp(t)
+
+	ta.dispatch(domain.GuiAttachedAction(context.Background()))
+	ta.selectRoster("d t a n x x x d d d d d x x")
+	ta.dispatch(domain.SelectCalendarAction(context.Background(), "Werk"))
+	ta.dispatch(domain.ImportEntriesToCalendar(context.Background()))
+	ta.checkNoErrors()
+
+	// an event of the import is removed by hand, or was never created
+	events := ta.backend.Events("calendar-1")
+
+	if err := ta.backend.DeleteEvent(context.Background(), "calendar-1", events[0].Id); err != nil {
+		t.Fatal(err)
+	}
+
+	ta.dispatch(domain.UndoLastImportAction(context.Background()))
+	ta.checkNoErrors()
+
+	if events := ta.backend.Events("calendar-1"); len(events) != 0 || ta.state.LastImport != "" {
+		t.Errorf("expected the whole import to be undone, got %d events and last import %q", len(events), ta.state.LastImport)
+	}
+}
+
+func TestUndoLastImport(t *testing.T) {
+	ta := newTestApp(t)
+
+	// a personal event, and a first import that should stay
+	_, err := ta.backend.CreateEvent(context.Background(), "calendar-2", &calendar.CalendarEvent{
+		Title: "Tandarts",
+		Start: time.Date(2024, 1, 1, 9, 0, 0, 0, time.Local),
+		End:   time.Date(2024, 1, 1, 9, 30, 0, 0, time.Local),
+	})
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ta.dispatch(domain.GuiAttachedAction(context.Background()))
+	ta.selectRoster("d t a n x x x d d d d d x x")
+	ta.dispatch(domain.SelectCalendarAction(context.Background(), "Werk"))
+	ta.dispatch(domain.ImportEntriesToCalendar(context.Background()))
+
+	// then it is imported again, into the wrong calendar
+	ta.dispatch(domain.SelectCalendarAction(context.Background(), "Prive"))
+	ta.dispatch(domain.ImportEntriesToCalendar(context.Background()))
+	ta.checkNoErrors()
+
+	if !strings.HasPrefix(ta.state.LastImport, "10 events from rooster.xlsx in Prive") {
+		t.Errorf("unexpected last import %q", ta.state.LastImport)
+	}
+
+	ta.dispatch(domain.UndoLastImportAction(context.Background()))
+	ta.checkNoErrors()
+
+	if events := ta.backend.Events("calendar-2"); len(events) != 1 || events[0].Title != "Tandarts" {
+		t.Errorf("expected only the personal event to remain, got %+v", events)
+	}
+
+	if events := ta.backend.Events("calendar-1"); len(events) != 10 {
+		t.Errorf("expected the earlier import to stay, got %d events", len(events))
+	}
+
+	if !strings.HasPrefix(ta.state.LastImport, "10 events from rooster.xlsx in Werk") {
+		t.Errorf("expected the earlier import to be next, got %q", ta.state.LastImport)
+	}
+
+	ta.dispatch(domain.UndoLastImportAction(context.Background()))
+	ta.dispatch(domain.UndoLastImportAction(context.Background()))
+
+	if len(ta.errors) != 1 || ta.state.LastImport != "" {
+		t.Errorf("expected an error when there is nothing left to undo, got %v (%q)", ta.errors, ta.state.LastImport)
+	}
+}
+
+// failingBackend fails to create events on a given day
+type failingBackend struct {
+	*calendartest.Backend
+	day int
+}
+
+func (b *failingBackend) CreateEvent(ctx context.Context, calendarId string, event *calendar.CalendarEvent) (*calendar.CalendarEvent, error) {
+	if event.Start.Day() == b.day {
+		return nil, errors.New("computer says no")
+	}
+
+	return b.Backend.CreateEvent(ctx, calendarId, event)
+}
+
+func TestImportReport(t *testing.T) {
+	ta := newTestApp(t)
+
+	ta.app = domain.NewApplication(&calendartest.Provider{
+		Backend:  &failingBackend{Backend: ta.backend, day: 2},
+		LoggedIn: true,
+	})
+
+	ta.dispatch(domain.GuiAttachedAction(context.Background()))
+	ta.selectRoster("d t a n x x x d d d d d x x")
+	ta.dispatch(domain.SelectCalendarAction(context.Background(), "Werk"))
+	ta.dispatch(domain.ImportEntriesToCalendar(context.Background()))
+
+	var report domain.Progress
+
+	for _, msg := range ta.messages {
+		if progress, ok := msg.(domain.Progress); ok && progress.Finished {
+			report = progress
+		}
+	}
+
+	if len(report.Results) != 10 {
+		t.Fatalf("expected a result for all 10 events, got %+v", report)
+	}
+
+	for _, result := range report.Results {
+		failed := result.Event.Start.Day() == 2
+
+		if result.Change != domain.ChangeCreate || failed != (result.Err != nil) {
+			t.Errorf("unexpected result %s %s: %v", result.Change, result.Event.Summary(), result.Err)
+		}
+	}
+
+	if len(ta.errors) != 1 || !strings.Contains(ta.errors[0].Error(), "1 of 10 changes failed") {
+		t.Errorf("expected the failure to be reported, got %v", ta.errors)
+	}
+}
+
+// slowBackend takes a while to create events, and keeps track of how many are created at the same time
+type slowBackend struct {
+	*calendartest.Backend
+	mu      sync.Mutex
+	running int
+	max     int
+}
+
+func (b *slowBackend) CreateEvent(ctx context.Context, calendarId string, event *calendar.CalendarEvent) (*calendar.CalendarEvent, error) {
+	b.mu.Lock()
+	b.running += 1
+	if b.running > b.max {
+		b.max = b.running
+	}
+	b.mu.Unlock()
+
+	time.Sleep(10 * time.Millisecond)
+
+	b.mu.Lock()
+	b.running -= 1
+	b.mu.Unlock()
+
+	return b.Backend.CreateEvent(ctx, calendarId, event)
+}
+
+func TestConcurrentImport(t *testing.T) {
+	ta := newTestApp(t)
+	slow := &slowBackend{Backend: ta.backend}
+
+	ta.app = domain.NewApplication(&calendartest.Provider{Backend: slow, LoggedIn: true})
+
+	ta.dispatch(domain.SetConcurrencyAction(3))
+	ta.dispatch(domain.GuiAttachedAction(context.Background()))
+	ta.selectRoster("d t a n x x x d d d d d x x")
+	ta.dispatch(domain.SelectCalendarAction(context.Background(), "Werk"))
+	ta.dispatch(domain.ImportEntriesToCalendar(context.Background()))
+	ta.checkNoErrors()
+
+	if slow.max != 3 {
+		t.Errorf("expected 3 events to be created at the same time, got %d", slow.max)
+	}
+
+	done := 0
+	var report domain.Progress
+
+	for _, msg := range ta.messages {
+		if progress, ok := msg.(domain.Progress); ok {
+			if !progress.Finished && progress.Done != done+1 {
+				t.Errorf("expected progress %d after %d, got %d", done+1, done, progress.Done)
+			}
+
+			done = progress.Done
+			report = progress
+		}
+	}
+
+	// the results are in the order of the roster, whatever order the events were created in
+	for i, result := range report.Results {
+		if result.Event != ta.state.ConvertedEvents[i] {
+			t.Errorf("result %d is for %s, expected %s", i, result.Event.Summary(), ta.state.ConvertedEvents[i].Summary())
+		}
+	}
+
+	if events := ta.backend.Events("calendar-1"); len(events) != 10 {
+		t.Errorf("expected 10 events in the calendar, got %d", len(events))
+	}
+}
+
+func TestImportPlan(t *testing.T) {
+	ta := newTestApp(t)
+
+	existing := []calendar.CalendarEvent{
+		// overlaps the day shift on the first day
+		{Title: "Tandarts", Start: time.Date(2024, 1, 1, 9, 0, 0, 0, time.Local), End: time.Date(2024, 1, 1, 9, 30, 0, 0, time.Local)},
+		// the shift on the second day is already in the calendar
+		{Title: "Tussen", Start: time.Date(2024, 1, 2, 11, 0, 0, 0, time.Local), End: time.Date(2024, 1, 2, 19, 30, 0, 0, time.Local)},
+	}
+
+	for _, event := range existing {
+		if _, err := ta.backend.CreateEvent(context.Background(), "calendar-1", &event); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	ta.dispatch(domain.GuiAttachedAction(context.Background()))
+	ta.selectRoster("d t a n x x x d d d d d x x")
+
+	if ta.state.Plan != nil {
+		t.Errorf("expected no plan before a calendar is selected, got %+v", ta.state.Plan)
+	}
+
+	ta.dispatch(domain.SelectCalendarAction(context.Background(), "Werk"))
+	ta.checkNoErrors()
+
+	plan := ta.state.Plan
+
+	if plan == nil || plan.Count(domain.PlanCreate) != 9 || plan.Count(domain.PlanSkip) != 1 || plan.Conflicts() != 1 {
+		t.Fatalf("expected 9 creates, 1 skip and 1 conflict, got:\n%s", plan.Table())
+	}
+
+	first := plan.Items[0]
+
+	if first.Action != domain.PlanCreate || !first.Conflict || first.Reason != "overlaps Tandarts" || first.Code != "d" {
+		t.Errorf("unexpected first item %+v", first)
+	}
+
+	if events := ta.backend.Events("calendar-1"); len(events) != 2 {
+		t.Errorf("expected planning not to change the calendar, got %d events", len(events))
+	}
+}
+
+func TestLayoutProfiles(t *testing.T) {
+	ta := newTestApp(t)
+
+	dir := filepath.Join(os.Getenv("XDG_CONFIG_HOME"), "rooster-importer")
+
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		t.Fatal(err)
+	}
+
+	profiles := `{"selected": "verticaal", "profiles": [
+		{"name": "verticaal", "orientation": "vertical"},
+		{"name": "rij 1", "dateRow": 1, "firstDataColumn": 3}
+	]}`
+
+	if err := os.WriteFile(filepath.Join(dir, "layouts.json"), []byte(profiles), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	ta.dispatch(domain.GuiAttachedAction(context.Background()))
+
+	if strings.Join(ta.state.Layouts, ",") != "auto,verticaal,rij 1" || ta.state.SelectedLayout != "verticaal" {
+		t.Fatalf("expected the profiles from the config, got %v with %s selected", ta.state.Layouts, ta.state.SelectedLayout)
+	}
+
+	ta.selectRoster("d t a n x x x d d d d d x x")
+	ta.checkNoErrors()
+
+	// the roster runs horizontally, so the vertical profile finds nothing
+	if len(ta.state.ConvertedEvents) != 0 {
+		t.Errorf("expected no events with the vertical profile, got %d", len(ta.state.ConvertedEvents))
+	}
+
+	ta.dispatch(domain.SelectLayoutAction(context.Background(), "rij 1"))
+	ta.checkNoErrors()
+
+	// the first data column skips the first day of the roster
+	if len(ta.state.ConvertedEvents) == 0 || !ta.state.ConvertedEvents[0].Date().Equal(time.Date(2024, 1, 2, 0, 0, 0, 0, time.UTC)) {
+		t.Errorf("expected the roster to start on the 2nd of January, got %v", ta.state.ConvertedEvents)
+	}
+
+	saved, err := domain.LoadLayoutProfiles()
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if saved.Selected != "rij 1" {
+		t.Errorf("expected the selected profile to be saved, got %s", saved.Selected)
+	}
+
+	ta.dispatch(domain.UseLayoutAction(context.Background(), "kolom 1"))
+
+	if len(ta.errors) != 1 || !strings.Contains(ta.errors[0].Error(), "auto, verticaal, rij 1") {
+		t.Fatalf("expected the unknown profile to be reported with the available profiles, got %v", ta.errors)
+	}
+}
+
+func TestInvalidLayoutProfiles(t *testing.T) {
+	ta := newTestApp(t)
+
+	dir := filepath.Join(os.Getenv("XDG_CONFIG_HOME"), "rooster-importer")
+
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		t.Fatal(err)
+	}
+
+	profiles := `{"profiles": [{"name": "auto"}, {"name": "schuin", "orientation": "sideways", "sheetPattern": "("}]}`
+
+	if err := os.WriteFile(filepath.Join(dir, "layouts.json"), []byte(profiles), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	ta.dispatch(domain.LoadLayoutAction())
+
+	var validationError *domain.LayoutValidationError
+
+	if len(ta.errors) != 1 || !errors.As(ta.errors[0], &validationError) || len(validationError.Problems) != 3 {
+		t.Fatalf("expected the three problems to be reported, got %v", ta.errors)
+	}
+
+	ta.errors = nil
+	ta.selectRoster("d t a n x x x d d d d d x x")
+	ta.checkNoErrors()
+
+	if len(ta.state.ConvertedEvents) == 0 {
+		t.Error("expected the layout to be detected after invalid profiles")
+	}
+}
+
+func TestOldLayoutFile(t *testing.T) {
+	ta := newTestApp(t)
+
+	dir := filepath.Join(os.Getenv("XDG_CONFIG_HOME"), "rooster-importer")
+
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := os.WriteFile(filepath.Join(dir, "layout.json"), []byte(`{"orientation": "vertical"}`), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	ta.dispatch(domain.GuiAttachedAction(context.Background()))
+	ta.checkNoErrors()
+
+	if strings.Join(ta.state.Layouts, ",") != "auto,layout" || ta.state.SelectedLayout != "layout" {
+		t.Fatalf("expected the old layout to be used as a profile, got %v with %s selected", ta.state.Layouts, ta.state.SelectedLayout)
+	}
+
+	// once the profiles are saved, they are read from their own file
+	ta.dispatch(domain.SelectLayoutAction(context.Background(), domain.AutoLayout))
+
+	if err := os.Remove(filepath.Join(dir, "layout.json")); err != nil {
+		t.Fatal(err)
+	}
+
+	saved, err := domain.LoadLayoutProfiles()
+
+	if err != nil || saved.Selected != domain.AutoLayout || len(saved.Profiles) != 1 {
+		t.Errorf("expected the converted profile to be saved, got %+v (%v)", saved, err)
+	}
+}
+
+func TestChooseNameAfterFile(t *testing.T) {
+	ta := newTestApp(t)
+
+	ta.dispatch(domain.GuiAttachedAction(context.Background()))
+	ta.selectRosterFor("d t a n x x x d d d d d x x", "")
+	ta.checkNoErrors()
+
+	if strings.Join(ta.state.Names, ",") != "Jan de Vries" {
+		t.Errorf("expected the people in the roster to be listed, got %v", ta.state.Names)
+	}
+
+	if len(ta.state.ConvertedEvents) != 0 {
+		t.Errorf("expected no events before choosing a name, got %d", len(ta.state.ConvertedEvents))
+	}
+
+	ta.dispatch(domain.SelectNameAction(context.Background(), "Jan de Vries"))
+	ta.checkNoErrors()
+
+	if ta.state.SelectedName != "Jan de Vries" || len(ta.state.ConvertedEvents) == 0 {
+		t.Errorf("expected the roster of Jan de Vries to be converted, got %d events", len(ta.state.ConvertedEvents))
+	}
+}
+
+func TestChooseBetweenMatchingNames(t *testing.T) {
+	ta := newTestApp(t)
+
+	dates := []string{"Naam"}
+
+	for day := 1; day <= 14; day++ {
+		dates = append(dates, fmt.Sprintf("2024-1-%d", day))
+	}
+
+	roster := strings.Join(dates, ",") + "\n" +
+		"Jan de Vries," + strings.Repeat("d,", 13) + "d\n" +
+		"Jan Bakker," + strings.Repeat("n,", 13) + "n\n"
+
+	ta.dispatch(domain.GuiAttachedAction(context.Background()))
+	ta.dispatch(domain.SelectedXlsxFileAction(context.Background(), io.NopCloser(strings.NewReader(roster)), "rooster.csv", "jan"))
+
+	var ambiguous *excelreader.AmbiguousNameError
+
+	if len(ta.errors) != 1 || !errors.As(ta.errors[0], &ambiguous) {
+		t.Fatalf("expected the name to be ambiguous, got %v", ta.errors)
+	}
+
+	if strings.Join(ambiguous.Candidates, ",") != "Jan de Vries,Jan Bakker" {
+		t.Errorf("expected both Jans to be candidates, got %v", ambiguous.Candidates)
+	}
+
+	if len(ta.state.ConvertedEvents) != 0 {
+		t.Errorf("expected no events for an ambiguous name, got %d", len(ta.state.ConvertedEvents))
+	}
+
+	ta.errors = nil
+	ta.dispatch(domain.SelectNameAction(context.Background(), "Jan Bakker"))
+	ta.checkNoErrors()
+
+	if len(ta.state.ConvertedEvents) == 0 || ta.state.ConvertedEvents[0].Code != "n" {
+		t.Errorf("expected the night shifts of Jan Bakker, got %v", ta.state.ConvertedEvents)
+	}
+}
+
+func TestExportTeam(t *testing.T) {
+	ta := newTestApp(t)
+
+	dates := []string{"Naam"}
+
+	for day := 1; day <= 14; day++ {
+		dates = append(dates, fmt.Sprintf("2024-1-%d", day))
+	}
+
+	roster := strings.Join(dates, ",") + "\n" +
+		"Jan de Vries," + strings.Repeat("d,", 13) + "q\n" +
+		"Zoë Bakker,d," + strings.Repeat("n,", 12) + "n\n"
+
+	dir := t.TempDir()
+
+	ta.dispatch(domain.GuiAttachedAction(context.Background()))
+	ta.dispatch(domain.SelectedXlsxFileAction(context.Background(), io.NopCloser(strings.NewReader(roster)), "rooster.csv", ""))
+	ta.dispatch(domain.ExportTeamIcsAction(dir, false))
+	ta.checkNoErrors()
+
+	if len(ta.state.Team) != 2 {
+		t.Fatalf("expected the rosters of 2 people, got %v", ta.state.Team)
+	}
+
+	jan, zoe := ta.state.Team[0], ta.state.Team[1]
+
+	if jan.File != "jan-de-vries.ics" || strings.Join(jan.Unmapped, ",") != "q" {
+		t.Errorf("expected the unmapped code of Jan to be reported, got %s", jan.Summary())
+	}
+
+	if zoe.File != "zoë-bakker.ics" || len(zoe.Unmapped) != 0 || len(zoe.Events) == 0 {
+		t.Errorf("expected the night shifts of Zoë, got %s", zoe.Summary())
+	}
+
+	// both work the day shift on the first day, which must stay two events when the files are imported in one calendar
+	uids := make(map[string]string)
+
+	for _, schedule := range ta.state.Team {
+		file, err := os.Open(filepath.Join(dir, schedule.File))
+
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		events, err := ics.Parse(file)
+		file.Close()
+
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if len(events) != len(schedule.Events) {
+			t.Errorf("expected %d events in %s, got %d", len(schedule.Events), schedule.File, len(events))
+		}
+
+		for _, event := range events {
+			if other, ok := uids[event.UID]; ok {
+				t.Errorf("the events of %s and %s have the same UID %s", other, schedule.Name, event.UID)
+			}
+
+			uids[event.UID] = schedule.Name
+		}
+	}
+
+	// exporting again doesn't overwrite the files without asking
+	ta.dispatch(domain.ExportTeamIcsAction(dir, false))
+
+	var existing *domain.ExistingFilesError
+
+	if len(ta.errors) != 1 || !errors.As(ta.errors[0], &existing) || len(existing.Files) != 2 {
+		t.Errorf("expected the existing files to be reported, got %v", ta.errors)
+	}
+
+	// a file that cannot be written doesn't stop the other from being written
+	ta.errors = nil
+	os.Remove(filepath.Join(dir, "jan-de-vries.ics"))
+	os.Mkdir(filepath.Join(dir, "jan-de-vries.ics"), 0o755)
+
+	ta.dispatch(domain.ExportTeamIcsAction(dir, true))
+
+	if len(ta.errors) != 1 || !strings.Contains(ta.errors[0].Error(), "Jan de Vries: not saved") {
+		t.Errorf("expected the roster of Jan de Vries not to be saved, got %v", ta.errors)
+	}
+
+	if ta.state.Team[0].Err == nil || ta.state.Team[1].Err != nil {
+		t.Errorf("expected only the roster of Jan de Vries to fail, got %s and %s", ta.state.Team[0].Summary(), ta.state.Team[1].Summary())
+	}
+}
+
+// monthlyRoster is a roster of Jan de Vries with a sheet per month of 2024, which has day shifts on the first two weeks
+func monthlyRoster(t *testing.T, months ...string) io.ReadCloser {
+	t.Helper()
+
+	file := excelize.NewFile()
+
+	for month, sheet := range months {
+		if month == 0 {
+			file.SetSheetName("Sheet1", sheet)
+		} else {
+			file.NewSheet(sheet)
+		}
+
+		file.SetCellValue(sheet, "A2", "Jan de Vries")
+
+		for day := 1; day <= 14; day++ {
+			datecell, _ := excelize.CoordinatesToCellName(day+1, 1)
+			shiftcell, _ := excelize.CoordinatesToCellName(day+1, 2)
+
+			file.SetCellValue(sheet, datecell, fmt.Sprintf("2024-%d-%d", month+1, day))
+			file.SetCellValue(sheet, shiftcell, "d")
+		}
+	}
+
+	buffer, err := file.WriteToBuffer()
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return io.NopCloser(buffer)
+}
+
+func TestSelectSheetsAndDates(t *testing.T) {
+	ta := newTestApp(t)
+
+	ta.dispatch(domain.GuiAttachedAction(context.Background()))
+	ta.dispatch(domain.SelectedXlsxFileAction(context.Background(), monthlyRoster(t, "Januari", "Februari"), "rooster.xlsx", "Jan"))
+	ta.checkNoErrors()
+
+	if strings.Join(ta.state.Sheets, ",") != "Januari,Februari" || len(ta.state.ConvertedEvents) != 28 {
+		t.Fatalf("expected the shifts of both sheets, got %d events in %v", len(ta.state.ConvertedEvents), ta.state.Sheets)
+	}
+
+	ta.dispatch(domain.SelectSheetsAction(context.Background(), []string{"Februari"}))
+	ta.checkNoErrors()
+
+	if len(ta.state.ConvertedEvents) != 14 || ta.state.ConvertedEvents[0].RosterDate.Month() != time.February {
+		t.Errorf("expected only the shifts in februari, got %v", ta.state.ConvertedEvents)
+	}
+
+	ta.dispatch(domain.SetDateRangeAction(context.Background(), time.Date(2024, 2, 8, 0, 0, 0, 0, time.Local), time.Time{}))
+	ta.checkNoErrors()
+
+	if len(ta.state.ConvertedEvents) != 7 || ta.state.ConvertedEvents[0].RosterDate.Day() != 8 {
+		t.Errorf("expected only the shifts from 8 februari, got %v", ta.state.ConvertedEvents)
+	}
+
+	ta.dispatch(domain.SelectSheetsAction(context.Background(), []string{"Maart"}))
+
+	if len(ta.errors) != 1 || len(ta.state.ConvertedEvents) != 7 {
+		t.Errorf("expected an unknown sheet to be refused, got %v", ta.errors)
+	}
+}
+
+func TestSynchronizeKeepsSkippedSheets(t *testing.T) {
+	ta := newTestApp(t)
+
+	ta.dispatch(domain.GuiAttachedAction(context.Background()))
+	ta.dispatch(domain.SetSyncModeAction(true))
+	ta.dispatch(domain.SelectedXlsxFileAction(context.Background(), monthlyRoster(t, "Januari", "Februari", "Maart"), "rooster.xlsx", "Jan"))
+	ta.dispatch(domain.SelectCalendarAction(context.Background(), "Werk"))
+	ta.dispatch(domain.ImportEntriesToCalendar(context.Background()))
+	ta.checkNoErrors()
+
+	imported := len(ta.backend.Events("calendar-1"))
+
+	if imported != 42 {
+		t.Fatalf("expected the shifts of three months to be imported, got %d", imported)
+	}
+
+	// februari is left out, so its shifts are not in the roster, but they weren't removed from it either
+	ta.dispatch(domain.SelectSheetsAction(context.Background(), []string{"Januari", "Maart"}))
+	ta.dispatch(domain.SelectCalendarAction(context.Background(), "Werk"))
+	ta.checkNoErrors()
+
+	if len(ta.state.EventsToDelete) != 0 {
+		t.Errorf("expected the shifts in februari to be kept, got %d events to delete", len(ta.state.EventsToDelete))
+	}
+
+	ta.dispatch(domain.ImportEntriesToCalendar(context.Background()))
+	ta.checkNoErrors()
+
+	if events := ta.backend.Events("calendar-1"); len(events) != imported {
+		t.Errorf("expected all %d events to be kept, got %d", imported, len(events))
+	}
+}
@@ -1,25 +1,43 @@
 package domain
 
 import (
-	"io"
+	"rooster-importer/pkg/calendar"
+	"rooster-importer/pkg/excelreader"
 	"time"
 )
 
 type Application struct {
-	xlsxfile             io.ReadCloser
+	sourceHash           string
 	selectedCalendarName string
 	selectedCalendarId   string
 	uistate              UIState
+	entries              []excelreader.ScheduleEntry
 	eventsForCalendar    []*ScheduleEvent
 	eventsInCalendar     []*ScheduleEvent
 	newEventsForCalendar []*ScheduleEvent
+	eventsToUpdate       []*ScheduleEvent
+	eventsToDelete       []*ScheduleEvent
+	mapping              *ShiftMapping
+	layouts              *LayoutProfiles
+	layout               excelreader.Layout
+	book                 excelreader.Workbook
+	sheets               []string
+	from                 time.Time
+	to                   time.Time
+	username             string
+	provider             calendar.Provider
+	concurrency          int
 
 	guistuff chan interface{}
 }
 
 type UIState struct {
-	SelectedXlsxFile     string
+	SelectedXlsxFile string
+	// Names are the people in the selected file, of which SelectedName is the user
+	Names                []string
+	SelectedName         string
 	IsLoggedIn           bool
+	CalendarProvider     string
 	SelectedCalendarName string
 	AvailableCalendars   []string
 	ImportButtonEnabled  bool
@@ -29,11 +47,52 @@ type UIState struct {
 	EventsNotAlreadyInCalendar []*ScheduleEvent
 	FreeDays                   []time.Time
 	SkippedDays                []time.Time
+
+	// When synchronizing, events that were imported before are updated or deleted when the roster changed
+	SyncMode       bool
+	EventsToUpdate []*ScheduleEvent
+	EventsToDelete []*ScheduleEvent
+
+	Shifts []ShiftDefinition
+
+	// Sheets are the sheets in the selected file, of which only SelectedSheets are read
+	Sheets         []string
+	SelectedSheets []string
+
+	// From and To limit the shifts that are converted to the shifts on those dates, when they are not zero
+	From time.Time
+	To   time.Time
+
+	// Layouts are the names of the layout profiles, of which SelectedLayout is used to read rosters
+	Layouts        []string
+	SelectedLayout string
+
+	// Plan tells what importing into the selected calendar would do, and is nil when no calendar is selected
+	Plan *ImportPlan
+
+	// LastImport describes the import that can be undone, and is empty when there is none
+	LastImport string
+
+	// Team are the rosters of everyone in the selected file, after they were exported
+	Team []TeamSchedule
 }
 
-func NewApplication() *Application {
+// NewApplication creates an application that imports events into the calendars of the given provider.
+func NewApplication(provider calendar.Provider) *Application {
+	layouts := DefaultLayoutProfiles()
+	layout, _ := layouts.Layout(AutoLayout)
+
 	return &Application{
-		guistuff: make(chan interface{}),
+		guistuff:    make(chan interface{}),
+		mapping:     DefaultShiftMapping(),
+		layouts:     layouts,
+		layout:      layout,
+		provider:    provider,
+		concurrency: DefaultConcurrency,
+		uistate: UIState{
+			Layouts:        layouts.Names(),
+			SelectedLayout: layout.Name,
+		},
 	}
 }
 
@@ -47,7 +106,24 @@ func (a *Application) GuiStuff() <-chan interface{} {
 	return a.guistuff
 }
 
+// DeduplicateEvents determines which events of the roster have to be written to the selected calendar, and plans the
+// import.
 func (a *Application) DeduplicateEvents() {
+	a.deduplicate()
+	a.uistate.Plan = a.plan()
+}
+
+func (a *Application) deduplicate() {
+	a.eventsToUpdate = []*ScheduleEvent{}
+	a.eventsToDelete = []*ScheduleEvent{}
+	a.uistate.EventsToUpdate = a.eventsToUpdate
+	a.uistate.EventsToDelete = a.eventsToDelete
+
+	if a.uistate.SyncMode && len(a.entries) > 0 {
+		a.synchronizeEvents()
+		return
+	}
+
 	if len(a.eventsForCalendar) == 0 {
 		a.newEventsForCalendar = make([]*ScheduleEvent, 0)
 		a.uistate.EventsNotAlreadyInCalendar = a.newEventsForCalendar
@@ -64,16 +140,23 @@ func (a *Application) DeduplicateEvents() {
 		return
 	}
 
-	existing := make(map[ScheduleEvent]bool)
+	// events are already in the calendar when they look the same, or when the importer created them for the same
+	// roster cell before, even if the user renamed them since
+	existing := make(map[eventKey]bool)
+	imported := make(map[shiftKey]bool)
 
 	for _, incalEvent := range a.eventsInCalendar {
-		existing[*incalEvent] = true
+		existing[incalEvent.key()] = true
+
+		if incalEvent.Managed && !incalEvent.RosterDate.IsZero() {
+			imported[incalEvent.shift()] = true
+		}
 	}
 
 	a.newEventsForCalendar = []*ScheduleEvent{}
 
 	for _, newEvent := range a.eventsForCalendar {
-		if _, exists := existing[*newEvent]; !exists {
+		if !existing[newEvent.key()] && !imported[newEvent.shift()] {
 			a.newEventsForCalendar = append(a.newEventsForCalendar, newEvent)
 		}
 	}
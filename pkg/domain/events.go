@@ -11,12 +11,92 @@ type ScheduleEvent struct {
 	Start        time.Time
 	End          time.Time
 	AllDay       bool
+
+	// RosterDate and Code tell which cell of the roster the event was converted from. For events in the calendar, they
+	// are only known when the event was created by the importer.
+	RosterDate time.Time
+	Code       string
+
+	// Id and Managed are only known for events that are already in the calendar. Managed events were created by the
+	// importer, and may be updated or deleted when synchronizing.
+	Id      string
+	Managed bool
+}
+
+// eventKey contains the fields that make two events the same, regardless of where they are stored. Times are compared
+// as instants, because calendars return them in their own time zone.
+type eventKey struct {
+	ScheduleType string
+	Start        int64
+	End          int64
+	AllDay       bool
+}
+
+func (e *ScheduleEvent) key() eventKey {
+	return eventKey{
+		ScheduleType: e.ScheduleType,
+		Start:        e.Start.Unix(),
+		End:          e.End.Unix(),
+		AllDay:       e.AllDay,
+	}
+}
+
+// shiftKey identifies the roster cell an event was converted from
+type shiftKey struct {
+	RosterDate string
+	Code       string
+}
+
+func (e *ScheduleEvent) shift() shiftKey {
+	return shiftKey{
+		RosterDate: e.RosterDate.Format(time.DateOnly),
+		Code:       e.Code,
+	}
+}
+
+// sameShift tells whether both events were converted from the same roster cell, and still have the same times. The
+// title is not compared, so that events renamed by the user are still recognized.
+func (e *ScheduleEvent) sameShift(other *ScheduleEvent) bool {
+	return !e.RosterDate.IsZero() && e.shift() == other.shift() &&
+		e.Start.Equal(other.Start) && e.End.Equal(other.End) && e.AllDay == other.AllDay
+}
+
+// Date is the day of the roster the event belongs to, or the day on which the event starts when that is not known
+func (e *ScheduleEvent) Date() time.Time {
+	if !e.RosterDate.IsZero() {
+		return e.RosterDate
+	}
+
+	if e.AllDay {
+		return dateToTime(e.Start)
+	}
+
+	local := e.Start.In(time.Local)
+
+	return time.Date(local.Year(), local.Month(), local.Day(), 0, 0, 0, 0, time.UTC)
 }
 
 func (e *ScheduleEvent) Summary() string {
 	return fmt.Sprintf("%s: %s (%s - %s)", e.ScheduleType, e.Start.Format("02/01"), e.Start.Format("15:04"), e.End.Format("15:04"))
 }
 
+// UID identifies the event by its date and shift, so that exporting the same roster twice yields the same identifiers.
+func (e *ScheduleEvent) UID() string {
+	shift := strings.ToLower(strings.ReplaceAll(e.ScheduleType, " ", "-"))
+
+	return fmt.Sprintf("%s-%s@rooster-importer", e.Start.Format("20060102"), shift)
+}
+
+// PersonUID identifies the event of a person by its date, shift and the person, so that the rosters of colleagues can be
+// imported into the same calendar. Without a person, it is the same as UID.
+func (e *ScheduleEvent) PersonUID(person string) string {
+	if person == "" {
+		return e.UID()
+	}
+
+	return strings.Replace(e.UID(), "@", "-"+nameSlug(person)+"@", 1)
+}
+
 type Conversion string
 
 const (
@@ -34,181 +114,50 @@ func dateToTime(date time.Time) time.Time {
 	return time.Date(date.Year(), date.Month(), date.Day(), 0, 0, 0, 0, time.UTC)
 }
 
-func NewScheduleEvent(excelEntry string, date time.Time) (*ScheduleEvent, Conversion) {
+// NewScheduleEvent converts the contents of a cell at a given date to an event using the mapping table.
+func (m *ShiftMapping) NewScheduleEvent(excelEntry string, date time.Time) (*ScheduleEvent, Conversion) {
 	weekday := timeAtDay(date, 16, 0).Weekday()
 	isWeekend := weekday == time.Saturday || weekday == time.Sunday
 
-	switch strings.ToLower(strings.Trim(excelEntry, " ")) {
-	case "":
-		fallthrough
-	case "x":
-		fallthrough
-	case "-":
-		fallthrough
-	case "-c":
-		conversion := ConversionVrij
-		if isWeekend {
-			conversion = ConversionSkipped
-		}
-
-		event := ScheduleEvent{
-			ScheduleType: "Vrij",
-			Start:        dateToTime(date),
-			End:          dateToTime(date.Add(24 * time.Hour)),
-			AllDay:       true,
-		}
-		return &event, conversion
-
-	case "d":
-		fallthrough
-	case "d (als)":
-		event := ScheduleEvent{
-			ScheduleType: "Dag",
-			Start:        timeAtDay(date, 7, 45),
-			End:          timeAtDay(date, 16, 15),
-			AllDay:       false,
-		}
-		return &event, ConversionConverted
-
-	case "t":
-		fallthrough
-	case "t (als)":
-		event := ScheduleEvent{
-			ScheduleType: "Tussen",
-			Start:        timeAtDay(date, 11, 0),
-			End:          timeAtDay(date, 19, 30),
-			AllDay:       false,
-		}
-		return &event, ConversionConverted
-
-	case "a":
-		event := ScheduleEvent{
-			ScheduleType: "Avond",
-			Start:        timeAtDay(date, 15, 0),
-			End:          timeAtDay(date, 23, 30),
-			AllDay:       false,
-		}
-		return &event, ConversionConverted
-
-	case "n":
-		event := ScheduleEvent{
-			ScheduleType: "Nacht",
-			Start:        timeAtDay(date, 23, 0),
-			End:          timeAtDay(date.Add(24*time.Hour), 8, 30),
-			AllDay:       false,
-		}
-		return &event, ConversionConverted
-
-	case "vak":
-		fallthrough
-	case "vak.":
-		event := ScheduleEvent{
-			ScheduleType: "Vakantie",
+	shift, ok := m.lookup(excelEntry)
+
+	if !ok {
+		shift = &m.Default
+	}
+
+	conversion := shift.Conversion
+
+	if isWeekend && shift.WeekendConversion != "" {
+		conversion = shift.WeekendConversion
+	}
+
+	if shift.AllDay {
+		return &ScheduleEvent{
+			ScheduleType: shift.Title,
 			Start:        dateToTime(date),
 			End:          dateToTime(date.Add(24 * time.Hour)),
 			AllDay:       true,
-		}
-		return &event, ConversionConverted
+			RosterDate:   dateToTime(date),
+			Code:         normalizeCode(excelEntry),
+		}, conversion
+	}
+
+	// times are validated when the mapping is loaded
+	starthours, startminutes, _ := parseClock(shift.Start)
+	endhours, endminutes, _ := parseClock(shift.End)
+
+	enddate := date
 
+	if shift.NextDayEnd {
+		enddate = date.Add(24 * time.Hour)
 	}
 
-	// default naar dagdienst met een waarschuwing als het roostertype niet herkent wordt.
 	return &ScheduleEvent{
-		ScheduleType: "Dag",
-		Start:        timeAtDay(date, 7, 45),
-		End:          timeAtDay(date, 16, 15),
+		ScheduleType: shift.Title,
+		Start:        timeAtDay(date, starthours, startminutes),
+		End:          timeAtDay(enddate, endhours, endminutes),
 		AllDay:       false,
-	}, ConversionDefaulted
-
-	// case "":
-	// 	// weekend dagen niet meenemen
-	// 	return nil, ConversionSkipped
-	// case "aanvraag verlof":
-	// 	fallthrough
-	// case "aanvraag vrij":
-	// 	fallthrough
-	// case "vak":
-	// 	fallthrough
-	// case "vk":
-	// 	// Vakantie dag
-	// 	event := ScheduleEvent{
-	// 		ScheduleType: "Vakantiedag",
-	// 		Start:        dateToTime(date),
-	// 		End:          dateToTime(date.Add(24 * time.Hour)),
-	// 		AllDay:       true,
-	// 	}
-	//
-	// 	return &event, ConversionVrij
-	// case "c":
-	// 	// Vrij/Compensatie
-	// 	event := ScheduleEvent{
-	// 		ScheduleType: "Compensatiedag",
-	// 		Start:        dateToTime(date),
-	// 		End:          dateToTime(date.Add(24 * time.Hour)),
-	// 		AllDay:       true,
-	// 	}
-	//
-	// 	return &event, ConversionVrij
-	//
-	// case "a":
-	// 	fallthrough
-	// case "wa":
-	// 	// (weekend) avond dienst
-	// 	event := ScheduleEvent{
-	// 		ScheduleType: "Avond",
-	// 		Start:        timeAtDay(date, 16, 0),
-	// 		End:          timeAtDay(date, 23, 59),
-	// 	}
-	//
-	// 	return &event, ConversionConverted
-	//
-	// case "n":
-	// 	fallthrough
-	// case "wn":
-	// 	// (weekend) nachtdienst
-	// 	event := ScheduleEvent{
-	// 		ScheduleType: "Nacht",
-	// 		Start:        timeAtDay(date, 23, 30),
-	// 		End:          timeAtDay(date.Add(time.Hour*24), 8, 30),
-	// 	}
-	//
-	// 	return &event, ConversionConverted
-	//
-	// case "wk":
-	// 	// weekend kort
-	// 	event := ScheduleEvent{
-	// 		ScheduleType: "Weekend kort",
-	// 		Start:        timeAtDay(date, 8, 0),
-	// 		End:          timeAtDay(date, 13, 0),
-	// 	}
-	//
-	// 	return &event, ConversionConverted
-	//
-	// case "1b":
-	// 	fallthrough
-	// case "1d":
-	// 	fallthrough
-	// case "ccu":
-	// 	fallthrough
-	// case "seh":
-	// 	fallthrough
-	// case "seh l":
-	// 	fallthrough
-	// case "seh c":
-	// 	// Normale dienst
-	// 	event := ScheduleEvent{
-	// 		ScheduleType: excelEntry,
-	// 		Start:        timeAtDay(date, 8, 0),
-	// 		End:          timeAtDay(date, 17, 0),
-	// 	}
-	//
-	// 	return &event, ConversionConverted
-	// }
-	//
-	// // Fall back to 8 - 17 but with a warning
-	// return &ScheduleEvent{
-	// 	ScheduleType: excelEntry,
-	// 	Start:        timeAtDay(date, 8, 0),
-	// 	End:          timeAtDay(date, 17, 0),
-	// }, ConversionDefaulted
+		RosterDate:   dateToTime(date),
+		Code:         normalizeCode(excelEntry),
+	}, conversion
 }
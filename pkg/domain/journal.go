@@ -0,0 +1,142 @@
+package domain
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"rooster-importer/pkg/calendar"
+	"time"
+)
+
+// journalFile keeps the events created by the last imports, so that an import into the wrong calendar can be undone. It
+// is stored next to the login of the calendar, in the user's cache directory.
+const journalFile = "journal.json"
+
+// maxJournalImports is the number of imports that are kept in the journal
+const maxJournalImports = 20
+
+type JournalEvent struct {
+	Id      string `json:"id"`
+	Summary string `json:"summary"`
+}
+
+// ImportRecord is the journal entry of a single import, with the events it created.
+type ImportRecord struct {
+	File         string         `json:"file"`
+	Provider     string         `json:"provider"`
+	CalendarId   string         `json:"calendarId"`
+	CalendarName string         `json:"calendarName"`
+	Time         time.Time      `json:"time"`
+	Events       []JournalEvent `json:"events"`
+}
+
+func (r *ImportRecord) Description() string {
+	return fmt.Sprintf("%d events from %s in %s (%s)", len(r.Events), filepath.Base(r.File), r.CalendarName, r.Time.Local().Format("02/01/2006 15:04"))
+}
+
+type importJournal struct {
+	Imports []ImportRecord `json:"imports"`
+}
+
+func journalLocation() (string, error) {
+	return calendar.CacheLocation(journalFile)
+}
+
+// loadJournal reads the journal, which is empty when nothing was imported yet.
+func loadJournal() (*importJournal, error) {
+	path, err := journalLocation()
+
+	if err != nil {
+		return nil, err
+	}
+
+	contents, err := os.ReadFile(path)
+
+	if errors.Is(err, os.ErrNotExist) {
+		return &importJournal{}, nil
+	} else if err != nil {
+		return nil, err
+	}
+
+	journal := &importJournal{}
+
+	if err := json.Unmarshal(contents, journal); err != nil {
+		return nil, fmt.Errorf("cannot decode %s: %w", path, err)
+	}
+
+	return journal, nil
+}
+
+func (j *importJournal) save() error {
+	path, err := journalLocation()
+
+	if err != nil {
+		return err
+	}
+
+	if len(j.Imports) > maxJournalImports {
+		j.Imports = j.Imports[len(j.Imports)-maxJournalImports:]
+	}
+
+	contents, err := json.MarshalIndent(j, "", "  ")
+
+	if err != nil {
+		return err
+	}
+
+	if err := os.WriteFile(path, append(contents, '\n'), 0600); err != nil {
+		return fmt.Errorf("cannot save %s: %w", path, err)
+	}
+
+	return nil
+}
+
+// last returns the most recent import, or nil when the journal is empty
+func (j *importJournal) last() *ImportRecord {
+	if len(j.Imports) == 0 {
+		return nil
+	}
+
+	return &j.Imports[len(j.Imports)-1]
+}
+
+// recordImport adds an import to the journal. Imports that didn't create events have nothing to undo, and are left out.
+func (a *Application) recordImport(record ImportRecord) error {
+	if len(record.Events) == 0 {
+		return nil
+	}
+
+	journal, err := loadJournal()
+
+	if err != nil {
+		return err
+	}
+
+	journal.Imports = append(journal.Imports, record)
+
+	if err := journal.save(); err != nil {
+		return err
+	}
+
+	a.uistate.LastImport = record.Description()
+
+	return nil
+}
+
+// loadLastImport shows the last import in the journal, so that it can be undone.
+func (a *Application) loadLastImport() {
+	a.uistate.LastImport = ""
+
+	journal, err := loadJournal()
+
+	if err != nil {
+		a.guistuff <- fmt.Errorf("cannot read the import journal: %w", err)
+		return
+	}
+
+	if last := journal.last(); last != nil {
+		a.uistate.LastImport = last.Description()
+	}
+}
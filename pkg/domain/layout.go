@@ -0,0 +1,230 @@
+package domain
+
+import (
+	"context"
+	"fmt"
+	"rooster-importer/pkg/config"
+	"rooster-importer/pkg/excelreader"
+	"strings"
+)
+
+const layoutsFile = "layouts.json"
+
+// oldLayoutFile is where a single layout was kept before there were profiles. It is read as a profile with the name
+// oldLayoutProfile, as long as no profiles were saved.
+const (
+	oldLayoutFile    = "layout.json"
+	oldLayoutProfile = "layout"
+)
+
+// AutoLayout is the name of the built in layout profile, which detects the layout of every sheet.
+const AutoLayout = "auto"
+
+// LayoutProfiles are named descriptions of how rosters are laid out, for rosters of which the layout isn't detected
+// correctly. Selected is the name of the profile that is used, the built in auto profile when it is empty.
+type LayoutProfiles struct {
+	Selected string               `json:"selected,omitempty"`
+	Profiles []excelreader.Layout `json:"profiles"`
+}
+
+type LayoutValidationError struct {
+	Problems []string
+}
+
+func (e *LayoutValidationError) Error() string {
+	return fmt.Sprintf("invalid layout profiles: %s", strings.Join(e.Problems, "; "))
+}
+
+// DefaultLayoutProfiles has no profiles besides the auto profile.
+func DefaultLayoutProfiles() *LayoutProfiles {
+	return &LayoutProfiles{Profiles: []excelreader.Layout{}}
+}
+
+// LoadLayoutProfiles reads the layout profiles from the user's config directory. When no profiles file exists, the
+// layout file of older versions is used instead, or else only the auto profile is available.
+func LoadLayoutProfiles() (*LayoutProfiles, error) {
+	profiles := &LayoutProfiles{}
+
+	err := config.Load(layoutsFile, profiles)
+
+	if config.IsNotExist(err) {
+		return loadOldLayout()
+	}
+
+	if err != nil {
+		return nil, err
+	}
+
+	if err := profiles.Validate(); err != nil {
+		return nil, err
+	}
+
+	return profiles, nil
+}
+
+// loadOldLayout converts the layout file of older versions into a selected profile, so that the layout keeps being
+// used. The file is left as it is, the profiles are saved to their own file once they change.
+func loadOldLayout() (*LayoutProfiles, error) {
+	layout := excelreader.Layout{}
+
+	err := config.Load(oldLayoutFile, &layout)
+
+	if config.IsNotExist(err) {
+		return DefaultLayoutProfiles(), nil
+	}
+
+	if err != nil {
+		return nil, err
+	}
+
+	// the layout only had an orientation, and without one it was detected like the auto profile does
+	if layout.Orientation == "" || layout.Orientation == excelreader.OrientationAuto {
+		return DefaultLayoutProfiles(), nil
+	}
+
+	layout.Name = oldLayoutProfile
+	profiles := &LayoutProfiles{Selected: layout.Name, Profiles: []excelreader.Layout{layout}}
+
+	if err := profiles.Validate(); err != nil {
+		return nil, err
+	}
+
+	return profiles, nil
+}
+
+// Save writes the layout profiles to the user's config directory.
+func (p *LayoutProfiles) Save() error {
+	if err := p.Validate(); err != nil {
+		return err
+	}
+
+	return config.Save(layoutsFile, p)
+}
+
+// Validate checks the profiles for mistakes, and reports all of them at once.
+func (p *LayoutProfiles) Validate() error {
+	problems := []string{}
+	names := map[string]bool{AutoLayout: true}
+
+	for i, profile := range p.Profiles {
+		switch {
+		case profile.Name == "":
+			problems = append(problems, fmt.Sprintf("profile %d has no name", i+1))
+		case profile.Name == AutoLayout:
+			problems = append(problems, fmt.Sprintf("profile %s is built in and cannot be changed", AutoLayout))
+		case names[profile.Name]:
+			problems = append(problems, fmt.Sprintf("profile %s is defined more than once", profile.Name))
+		}
+
+		names[profile.Name] = true
+
+		for _, problem := range profile.Validate() {
+			problems = append(problems, fmt.Sprintf("%s: %s", profile.Name, problem))
+		}
+	}
+
+	if p.Selected != "" && !names[p.Selected] {
+		problems = append(problems, fmt.Sprintf("selected profile %s does not exist", p.Selected))
+	}
+
+	if len(problems) > 0 {
+		return &LayoutValidationError{Problems: problems}
+	}
+
+	return nil
+}
+
+// Names returns the names of all profiles, starting with the auto profile.
+func (p *LayoutProfiles) Names() []string {
+	names := []string{AutoLayout}
+
+	for _, profile := range p.Profiles {
+		names = append(names, profile.Name)
+	}
+
+	return names
+}
+
+// Layout returns the profile with the given name.
+func (p *LayoutProfiles) Layout(name string) (excelreader.Layout, bool) {
+	if name == "" || name == AutoLayout {
+		return excelreader.Layout{Name: AutoLayout}, true
+	}
+
+	for _, profile := range p.Profiles {
+		if profile.Name == name {
+			return profile, true
+		}
+	}
+
+	return excelreader.Layout{}, false
+}
+
+// LoadLayoutAction reads the layout profiles from the config directory, and uses the selected profile. Invalid
+// profiles are reported, after which the auto profile is used.
+func LoadLayoutAction() Action {
+	return func(a *Application) {
+		a.loadLayouts()
+
+		a.guistuff <- NewState(a.uistate)
+	}
+}
+
+func (a *Application) loadLayouts() {
+	profiles, err := LoadLayoutProfiles()
+
+	if err != nil {
+		profiles = DefaultLayoutProfiles()
+		a.guistuff <- fmt.Errorf("cannot load layout profiles, detecting the layout instead: %w", err)
+	}
+
+	a.layouts = profiles
+	a.layout, _ = profiles.Layout(profiles.Selected)
+	a.uistate.Layouts = profiles.Names()
+	a.uistate.SelectedLayout = a.layout.Name
+}
+
+// SelectLayoutAction reads the selected roster again using another layout profile, and remembers the profile for the
+// next time.
+func SelectLayoutAction(ctx context.Context, name string) Action {
+	return func(a *Application) {
+		if !a.useLayout(name) {
+			return
+		}
+
+		a.layouts.Selected = a.layout.Name
+
+		if err := a.layouts.Save(); err != nil {
+			a.guistuff <- fmt.Errorf("cannot save the selected layout profile: %w", err)
+		}
+
+		a.readEntries(ctx)
+
+		a.guistuff <- NewState(a.uistate)
+	}
+}
+
+// UseLayoutAction reads rosters using another layout profile, without remembering it.
+func UseLayoutAction(ctx context.Context, name string) Action {
+	return func(a *Application) {
+		if a.useLayout(name) {
+			a.readEntries(ctx)
+
+			a.guistuff <- NewState(a.uistate)
+		}
+	}
+}
+
+func (a *Application) useLayout(name string) bool {
+	layout, ok := a.layouts.Layout(name)
+
+	if !ok {
+		a.guistuff <- fmt.Errorf("unknown layout profile %s, choose one of %s", name, strings.Join(a.layouts.Names(), ", "))
+		return false
+	}
+
+	a.layout = layout
+	a.uistate.SelectedLayout = layout.Name
+
+	return true
+}
@@ -0,0 +1,222 @@
+package domain
+
+import (
+	"fmt"
+	"rooster-importer/pkg/config"
+	"strings"
+	"time"
+)
+
+const mappingFile = "mapping.json"
+
+// ShiftDefinition describes how the contents of a cell in the roster translate to a calendar event.
+type ShiftDefinition struct {
+	Code    string   `json:"code"`
+	Aliases []string `json:"aliases,omitempty"`
+	Title   string   `json:"title"`
+
+	// Start and End are formatted as 15:04, and are ignored for all day events
+	Start      string `json:"start,omitempty"`
+	End        string `json:"end,omitempty"`
+	AllDay     bool   `json:"allDay,omitempty"`
+	NextDayEnd bool   `json:"nextDayEnd,omitempty"`
+
+	Conversion Conversion `json:"conversion"`
+	// WeekendConversion overrides Conversion on saturdays and sundays when set
+	WeekendConversion Conversion `json:"weekendConversion,omitempty"`
+}
+
+// ShiftMapping is the table that maps cell contents to shifts. Cells that don't match any of the shifts are converted
+// using the Default shift, and are marked as ConversionDefaulted.
+type ShiftMapping struct {
+	Shifts  []ShiftDefinition `json:"shifts"`
+	Default ShiftDefinition   `json:"default"`
+
+	index map[string]*ShiftDefinition
+}
+
+type MappingValidationError struct {
+	Problems []string
+}
+
+func (e *MappingValidationError) Error() string {
+	return fmt.Sprintf("invalid shift mapping: %s", strings.Join(e.Problems, "; "))
+}
+
+func DefaultShiftMapping() *ShiftMapping {
+	mapping := &ShiftMapping{
+		Shifts: []ShiftDefinition{
+			{Code: "x", Aliases: []string{"", "-", "-c"}, Title: "Vrij", AllDay: true, Conversion: ConversionVrij, WeekendConversion: ConversionSkipped},
+			{Code: "d", Aliases: []string{"d (als)"}, Title: "Dag", Start: "07:45", End: "16:15", Conversion: ConversionConverted},
+			{Code: "t", Aliases: []string{"t (als)"}, Title: "Tussen", Start: "11:00", End: "19:30", Conversion: ConversionConverted},
+			{Code: "a", Title: "Avond", Start: "15:00", End: "23:30", Conversion: ConversionConverted},
+			{Code: "n", Title: "Nacht", Start: "23:00", End: "08:30", NextDayEnd: true, Conversion: ConversionConverted},
+			{Code: "vak", Aliases: []string{"vak."}, Title: "Vakantie", AllDay: true, Conversion: ConversionConverted},
+		},
+		// default naar dagdienst met een waarschuwing als het roostertype niet herkent wordt.
+		Default: ShiftDefinition{Title: "Dag", Start: "07:45", End: "16:15", Conversion: ConversionDefaulted},
+	}
+
+	// the built in table is known to be valid
+	mapping.Validate()
+
+	return mapping
+}
+
+// LoadShiftMapping reads the mapping table from the user's config directory. When no mapping file exists, the built in
+// default mapping is returned.
+func LoadShiftMapping() (*ShiftMapping, error) {
+	mapping := &ShiftMapping{}
+
+	err := config.Load(mappingFile, mapping)
+
+	if config.IsNotExist(err) {
+		return DefaultShiftMapping(), nil
+	}
+
+	if err != nil {
+		return nil, err
+	}
+
+	if err := mapping.Validate(); err != nil {
+		return nil, err
+	}
+
+	return mapping, nil
+}
+
+// Save writes the mapping table to the user's config directory.
+func (m *ShiftMapping) Save() error {
+	if err := m.Validate(); err != nil {
+		return err
+	}
+
+	return config.Save(mappingFile, m)
+}
+
+func normalizeCode(code string) string {
+	return strings.ToLower(strings.Trim(code, " "))
+}
+
+func parseClock(clock string) (hours, minutes int, err error) {
+	t, err := time.Parse("15:04", clock)
+
+	if err != nil {
+		return 0, 0, fmt.Errorf("%q is not a time (expected hh:mm)", clock)
+	}
+
+	return t.Hour(), t.Minute(), nil
+}
+
+func validConversion(c Conversion) bool {
+	switch c {
+	case ConversionVrij, ConversionConverted, ConversionDefaulted, ConversionSkipped:
+		return true
+	}
+	return false
+}
+
+func (d *ShiftDefinition) validate() []string {
+	problems := []string{}
+	name := d.Code
+
+	if name == "" {
+		name = d.Title
+	}
+
+	if d.Title == "" {
+		problems = append(problems, fmt.Sprintf("shift %q has no title", name))
+	}
+
+	if !validConversion(d.Conversion) {
+		problems = append(problems, fmt.Sprintf("shift %q has unknown conversion %q", name, d.Conversion))
+	}
+
+	if d.WeekendConversion != "" && !validConversion(d.WeekendConversion) {
+		problems = append(problems, fmt.Sprintf("shift %q has unknown weekend conversion %q", name, d.WeekendConversion))
+	}
+
+	if d.AllDay {
+		return problems
+	}
+
+	starthours, startminutes, err := parseClock(d.Start)
+
+	if err != nil {
+		problems = append(problems, fmt.Sprintf("shift %q start: %s", name, err))
+	}
+
+	endhours, endminutes, err := parseClock(d.End)
+
+	if err != nil {
+		problems = append(problems, fmt.Sprintf("shift %q end: %s", name, err))
+	}
+
+	if len(problems) == 0 && !d.NextDayEnd && endhours*60+endminutes <= starthours*60+startminutes {
+		problems = append(problems, fmt.Sprintf("shift %q ends before it starts (is it an overnight shift?)", name))
+	}
+
+	return problems
+}
+
+func validateShifts(shifts []ShiftDefinition) (map[string]*ShiftDefinition, []string) {
+	problems := []string{}
+	index := make(map[string]*ShiftDefinition)
+
+	for i := range shifts {
+		shift := &shifts[i]
+
+		if normalizeCode(shift.Code) == "" {
+			problems = append(problems, fmt.Sprintf("shift %q has no code", shift.Title))
+		}
+
+		problems = append(problems, shift.validate()...)
+
+		for _, code := range append([]string{shift.Code}, shift.Aliases...) {
+			key := normalizeCode(code)
+
+			if other, exists := index[key]; exists {
+				problems = append(problems, fmt.Sprintf("code %q is used by both %q and %q", code, other.Title, shift.Title))
+				continue
+			}
+
+			index[key] = shift
+		}
+	}
+
+	return index, problems
+}
+
+// ValidateShifts checks a list of shifts for mistakes, without the default shift of a mapping table.
+func ValidateShifts(shifts []ShiftDefinition) error {
+	if _, problems := validateShifts(shifts); len(problems) > 0 {
+		return &MappingValidationError{Problems: problems}
+	}
+
+	return nil
+}
+
+// Validate checks the mapping table for mistakes, and prepares it for looking up cell contents.
+func (m *ShiftMapping) Validate() error {
+	index, problems := validateShifts(m.Shifts)
+
+	problems = append(problems, m.Default.validate()...)
+
+	if len(problems) > 0 {
+		return &MappingValidationError{Problems: problems}
+	}
+
+	m.index = index
+
+	return nil
+}
+
+func (m *ShiftMapping) lookup(excelEntry string) (*ShiftDefinition, bool) {
+	if m.index == nil && m.Validate() != nil {
+		return nil, false
+	}
+
+	shift, ok := m.index[normalizeCode(excelEntry)]
+
+	return shift, ok
+}
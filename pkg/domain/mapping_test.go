@@ -0,0 +1,65 @@
+package domain_test
+
+import (
+	"errors"
+	"rooster-importer/pkg/domain"
+	"testing"
+	"time"
+)
+
+func TestDefaultMapping(t *testing.T) {
+	mapping := domain.DefaultShiftMapping()
+
+	// 2024-01-05 is a friday, 2024-01-06 a saturday
+	friday := time.Date(2024, 1, 5, 0, 0, 0, 0, time.UTC)
+	saturday := friday.Add(24 * time.Hour)
+
+	cases := []struct {
+		cell       string
+		date       time.Time
+		title      string
+		conversion domain.Conversion
+	}{
+		{"D", friday, "Dag", domain.ConversionConverted},
+		{" t (als) ", friday, "Tussen", domain.ConversionConverted},
+		{"vak.", friday, "Vakantie", domain.ConversionConverted},
+		{"", friday, "Vrij", domain.ConversionVrij},
+		{"", saturday, "Vrij", domain.ConversionSkipped},
+		{"onbekend", friday, "Dag", domain.ConversionDefaulted},
+	}
+
+	for _, c := range cases {
+		event, conversion := mapping.NewScheduleEvent(c.cell, c.date)
+
+		if event.ScheduleType != c.title || conversion != c.conversion {
+			t.Errorf("%q: expected %s (%s), got %s (%s)", c.cell, c.title, c.conversion, event.ScheduleType, conversion)
+		}
+	}
+
+	night, _ := mapping.NewScheduleEvent("n", friday)
+
+	if night.End.Day() != 6 || night.End.Hour() != 8 || night.End.Minute() != 30 {
+		t.Errorf("night shift should end the next morning, ends at %s", night.End)
+	}
+}
+
+func TestMappingValidation(t *testing.T) {
+	mapping := domain.DefaultShiftMapping()
+	mapping.Shifts = append(mapping.Shifts,
+		domain.ShiftDefinition{Code: "D", Title: "Dubbel", Start: "08:00", End: "17:00", Conversion: domain.ConversionConverted},
+		domain.ShiftDefinition{Code: "wn", Title: "Weekend nacht", Start: "23:00", End: "08:30", Conversion: domain.ConversionConverted},
+		domain.ShiftDefinition{Code: "wk", Title: "Weekend kort", Start: "8 uur", End: "13:00", Conversion: domain.ConversionConverted},
+	)
+
+	err := mapping.Validate()
+
+	var validationError *domain.MappingValidationError
+
+	if !errors.As(err, &validationError) {
+		t.Fatalf("expected validation errors, got %v", err)
+	}
+
+	if len(validationError.Problems) != 3 {
+		t.Errorf("expected 3 problems, got: %s", err)
+	}
+}
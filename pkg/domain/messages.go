@@ -1,5 +1,10 @@
 package domain
 
+import (
+	"fmt"
+	"strings"
+)
+
 type Information string
 
 type NewState UIState
@@ -7,4 +12,64 @@ type NewState UIState
 type Progress struct {
 	Done  int
 	Total int
+
+	// Finished is set on the last progress, which also holds the result of every event that was written. Canceled
+	// is set when the events were not all written because of canceling.
+	Finished bool
+	Canceled bool
+	Results  []EventResult
+}
+
+type ChangeKind string
+
+const (
+	ChangeCreate ChangeKind = "create"
+	ChangeUpdate ChangeKind = "update"
+	ChangeDelete ChangeKind = "delete"
+)
+
+// EventResult is the outcome of writing a single event to the calendar. Err is nil when it succeeded.
+type EventResult struct {
+	Event  *ScheduleEvent
+	Change ChangeKind
+	Err    error
+}
+
+// ImportCanceledError is sent when an import is canceled, and tells exactly which changes were made to the calendar
+// before that.
+type ImportCanceledError struct {
+	Created []*ScheduleEvent
+	Updated []*ScheduleEvent
+	Deleted []*ScheduleEvent
+	// Unknown are the events that were being created when the import was canceled, which may exist in the calendar.
+	// They are in the journal, so undoing the import removes them if they do.
+	Unknown []*ScheduleEvent
+}
+
+func (e *ImportCanceledError) Error() string {
+	b := strings.Builder{}
+
+	fmt.Fprintf(&b, "import canceled after creating %d, updating %d and deleting %d events", len(e.Created), len(e.Updated), len(e.Deleted))
+
+	if len(e.Unknown) > 0 {
+		fmt.Fprintf(&b, ", %d events may have been created, undo the import to remove them", len(e.Unknown))
+	}
+
+	for _, event := range e.Created {
+		b.WriteString("\ncreated " + event.Summary())
+	}
+
+	for _, event := range e.Updated {
+		b.WriteString("\nupdated " + event.Summary())
+	}
+
+	for _, event := range e.Deleted {
+		b.WriteString("\ndeleted " + event.Summary())
+	}
+
+	for _, event := range e.Unknown {
+		b.WriteString("\nmaybe created " + event.Summary())
+	}
+
+	return b.String()
 }
@@ -0,0 +1,196 @@
+package domain
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+	"text/tabwriter"
+	"time"
+)
+
+type PlanAction string
+
+const (
+	PlanCreate PlanAction = "create"
+	PlanUpdate PlanAction = "update"
+	PlanDelete PlanAction = "delete"
+	PlanSkip   PlanAction = "skip"
+)
+
+// PlanItem is what an import would do with a single event. Conflicting events would still be written, but overlap
+// with another event in the calendar.
+type PlanItem struct {
+	Action   PlanAction `json:"action"`
+	Date     string     `json:"date"`
+	Code     string     `json:"code,omitempty"`
+	Title    string     `json:"title"`
+	Start    time.Time  `json:"start"`
+	End      time.Time  `json:"end"`
+	AllDay   bool       `json:"allDay"`
+	Conflict bool       `json:"conflict"`
+	Reason   string     `json:"reason,omitempty"`
+}
+
+// ImportPlan lists what importing the roster into the selected calendar would do, without changing the calendar.
+type ImportPlan struct {
+	Calendar string     `json:"calendar"`
+	SyncMode bool       `json:"syncMode"`
+	Items    []PlanItem `json:"items"`
+}
+
+func newPlanItem(action PlanAction, event *ScheduleEvent, reason string) PlanItem {
+	return PlanItem{
+		Action: action,
+		Date:   event.Date().Format(time.DateOnly),
+		Code:   event.Code,
+		Title:  event.ScheduleType,
+		Start:  event.Start,
+		End:    event.End,
+		AllDay: event.AllDay,
+		Reason: reason,
+	}
+}
+
+func overlaps(a, b *ScheduleEvent) bool {
+	return a.Start.Before(b.End) && b.Start.Before(a.End)
+}
+
+// plan describes the result of deduplicating the roster with the selected calendar. There is no plan until a calendar
+// is selected.
+func (a *Application) plan() *ImportPlan {
+	if a.selectedCalendarId == "" {
+		return nil
+	}
+
+	plan := &ImportPlan{
+		Calendar: a.selectedCalendarName,
+		SyncMode: a.uistate.SyncMode,
+		Items:    []PlanItem{},
+	}
+
+	creating := make(map[*ScheduleEvent]bool)
+	updating := make(map[shiftKey]bool)
+	removed := make(map[string]bool)
+
+	for _, event := range a.newEventsForCalendar {
+		creating[event] = true
+	}
+
+	for _, event := range a.eventsToUpdate {
+		updating[event.shift()] = true
+		removed[event.Id] = true
+	}
+
+	for _, event := range a.eventsToDelete {
+		removed[event.Id] = true
+	}
+
+	// conflict tells which events that stay in the calendar are overlapped by a shift that is written
+	conflict := func(event *ScheduleEvent) string {
+		if event.AllDay {
+			return ""
+		}
+
+		titles := []string{}
+
+		for _, existing := range a.eventsInCalendar {
+			if !removed[existing.Id] && existing.key() != event.key() && overlaps(event, existing) {
+				titles = append(titles, existing.ScheduleType)
+			}
+		}
+
+		if len(titles) == 0 {
+			return ""
+		}
+
+		return "overlaps " + strings.Join(titles, ", ")
+	}
+
+	for _, event := range a.eventsForCalendar {
+		var item PlanItem
+
+		switch {
+		case creating[event]:
+			item = newPlanItem(PlanCreate, event, conflict(event))
+		case updating[event.shift()]:
+			item = newPlanItem(PlanUpdate, event, conflict(event))
+		default:
+			item = newPlanItem(PlanSkip, event, "already in calendar")
+		}
+
+		item.Conflict = item.Action != PlanSkip && item.Reason != ""
+		plan.Items = append(plan.Items, item)
+	}
+
+	for _, event := range a.eventsToDelete {
+		plan.Items = append(plan.Items, newPlanItem(PlanDelete, event, "no longer in roster"))
+	}
+
+	sort.SliceStable(plan.Items, func(i, j int) bool {
+		return plan.Items[i].Date < plan.Items[j].Date
+	})
+
+	return plan
+}
+
+// Count returns the number of items with the given action.
+func (p *ImportPlan) Count(action PlanAction) int {
+	count := 0
+
+	for _, item := range p.Items {
+		if item.Action == action {
+			count += 1
+		}
+	}
+
+	return count
+}
+
+// Conflicts returns the number of events that would be written while overlapping other events.
+func (p *ImportPlan) Conflicts() int {
+	count := 0
+
+	for _, item := range p.Items {
+		if item.Conflict {
+			count += 1
+		}
+	}
+
+	return count
+}
+
+func (p *ImportPlan) JSON() ([]byte, error) {
+	return json.MarshalIndent(p, "", "  ")
+}
+
+// Table formats the plan as a table with a line per event, followed by the totals.
+func (p *ImportPlan) Table() string {
+	table := strings.Builder{}
+	w := tabwriter.NewWriter(&table, 0, 0, 2, ' ', 0)
+
+	fmt.Fprintln(w, "ACTION\tDATE\tSHIFT\tTIME\tNOTE")
+
+	for _, item := range p.Items {
+		times := "all day"
+
+		if !item.AllDay {
+			times = item.Start.Format("15:04") + "-" + item.End.Format("15:04")
+		}
+
+		note := item.Reason
+
+		if item.Conflict {
+			note = "conflict: " + note
+		}
+
+		fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\n", item.Action, item.Date, item.Title, times, note)
+	}
+
+	w.Flush()
+
+	fmt.Fprintf(&table, "\n%d to create, %d to update, %d to delete, %d to skip, %d conflicts\n",
+		p.Count(PlanCreate), p.Count(PlanUpdate), p.Count(PlanDelete), p.Count(PlanSkip), p.Conflicts())
+
+	return table.String()
+}
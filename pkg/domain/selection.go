@@ -0,0 +1,98 @@
+package domain
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"rooster-importer/pkg/excelreader"
+	"strings"
+	"time"
+)
+
+// SelectSheetsAction reads the selected file again using only the given sheets, for example to leave out the months
+// that were imported before.
+func SelectSheetsAction(ctx context.Context, sheets []string) Action {
+	return func(a *Application) {
+		if len(sheets) == 0 {
+			a.guistuff <- errors.New("select at least one sheet")
+			a.guistuff <- NewState(a.uistate)
+			return
+		}
+
+		if a.book != nil {
+			for _, sheet := range sheets {
+				if !contains(a.book.Sheets(), sheet) {
+					a.guistuff <- fmt.Errorf("the roster has no sheet %s, choose from %s", sheet, strings.Join(a.book.Sheets(), ", "))
+					a.guistuff <- NewState(a.uistate)
+					return
+				}
+			}
+		}
+
+		a.sheets = sheets
+		a.uistate.SelectedSheets = sheets
+
+		a.readEntries(ctx)
+
+		a.guistuff <- NewState(a.uistate)
+	}
+}
+
+// SetDateRangeAction only converts the shifts from the date from until and including the date to, so that old shifts
+// are not imported again. A zero from or to leaves the range open at that end.
+func SetDateRangeAction(ctx context.Context, from, to time.Time) Action {
+	return func(a *Application) {
+		if !from.IsZero() && !to.IsZero() && to.Before(from) {
+			a.guistuff <- fmt.Errorf("the end date %s is before the start date %s", to.Format("2-1-2006"), from.Format("2-1-2006"))
+			a.guistuff <- NewState(a.uistate)
+			return
+		}
+
+		a.from, a.to = from, to
+		a.uistate.From, a.uistate.To = from, to
+
+		a.readEntries(ctx)
+
+		a.guistuff <- NewState(a.uistate)
+	}
+}
+
+// sheetBook is the selected file with only the selected sheets.
+func (a *Application) sheetBook() excelreader.Workbook {
+	return excelreader.SelectSheets(a.book, a.sheets)
+}
+
+// inDateRange leaves out the entries outside of the selected date range.
+func (a *Application) inDateRange(entries []excelreader.ScheduleEntry) []excelreader.ScheduleEntry {
+	if a.from.IsZero() && a.to.IsZero() {
+		return entries
+	}
+
+	selected := []excelreader.ScheduleEntry{}
+
+	for _, entry := range entries {
+		date := dateToTime(entry.Date)
+
+		if !a.from.IsZero() && date.Before(dateToTime(a.from)) {
+			continue
+		}
+
+		if !a.to.IsZero() && date.After(dateToTime(a.to)) {
+			continue
+		}
+
+		selected = append(selected, entry)
+	}
+
+	return selected
+}
+
+func contains(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+
+	return false
+}
@@ -0,0 +1,98 @@
+package domain
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Summary describes the events converted from the selected Excel file, and which of those are not in the selected
+// calendar yet.
+func (s *UIState) Summary() string {
+	// Build text block for event summary
+	convertedCount := len(s.ConvertedEvents)
+	warningCount := len(s.WarningEvents)
+	freeDayCount := len(s.FreeDays)
+	skippedCount := len(s.SkippedDays)
+	newEventCount := len(s.EventsNotAlreadyInCalendar)
+
+	previewlines := strings.Builder{}
+
+	if convertedCount+freeDayCount > 0 {
+		previewlines.WriteString(fmt.Sprintf("Schedule conversion summary:\nNew Schedule events: %d", convertedCount))
+
+		if warningCount > 0 {
+			previewlines.WriteString(fmt.Sprintf(" (%d not sure of time)", warningCount))
+		}
+
+		previewlines.WriteString(fmt.Sprintf("\nEvents free: %d\nTotal things processed: %d\n", freeDayCount, convertedCount+freeDayCount))
+	}
+
+	// Build a preview of the first and last events to be added to the calendar
+	if convertedCount > 0 {
+		previewlines.WriteString("\nFirst event: ")
+		previewlines.WriteString(s.ConvertedEvents[0].Summary())
+	}
+
+	if convertedCount > 1 {
+		previewlines.WriteString("\nLast event: ")
+		previewlines.WriteString(s.ConvertedEvents[convertedCount-1].Summary())
+	}
+
+	previewlines.WriteString("\n")
+
+	previewlines.WriteString(fmt.Sprintf("\nNew events: %d\n", newEventCount))
+
+	if newEventCount > 0 {
+		previewlines.WriteString(fmt.Sprintf("First event: %s\n", s.EventsNotAlreadyInCalendar[0].Summary()))
+
+		if newEventCount > 1 {
+			previewlines.WriteString(fmt.Sprintf("Last event: %s\n", s.EventsNotAlreadyInCalendar[newEventCount-1].Summary()))
+		}
+
+		for _, event := range s.EventsNotAlreadyInCalendar {
+			previewlines.WriteString(fmt.Sprintf("%s\n", event.Summary()))
+		}
+	}
+
+	if s.SyncMode {
+		previewlines.WriteString(fmt.Sprintf("\nChanged events: %d\n", len(s.EventsToUpdate)))
+
+		for _, event := range s.EventsToUpdate {
+			previewlines.WriteString(fmt.Sprintf("%s\n", event.Summary()))
+		}
+
+		previewlines.WriteString(fmt.Sprintf("\nRemoved events: %d\n", len(s.EventsToDelete)))
+
+		for _, event := range s.EventsToDelete {
+			previewlines.WriteString(fmt.Sprintf("%s\n", event.Summary()))
+		}
+	}
+
+	if s.Plan != nil && len(s.Plan.Items) > 0 {
+		previewlines.WriteString(fmt.Sprintf("\nImport plan for %s:\n", s.Plan.Calendar))
+		previewlines.WriteString(s.Plan.Table())
+	}
+
+	if warningCount > 0 {
+		previewlines.WriteString("\nEvents where time is not explicit:\n")
+
+		for _, warning := range s.WarningEvents {
+			previewlines.WriteString(warning.Summary())
+			previewlines.WriteString("\n")
+		}
+	}
+
+	if freeDayCount > 0 || skippedCount > 0 {
+		previewlines.WriteString(fmt.Sprintf("\nFree dates (%d weekends not included):\n", skippedCount))
+
+		for i, date := range s.FreeDays {
+			previewlines.WriteString(fmt.Sprintf("%s  ", date.Format("02/01")))
+
+			if i%7 == 6 {
+				previewlines.WriteString("\n")
+			}
+		}
+	}
+
+	return previewlines.String()
+}
@@ -0,0 +1,104 @@
+package domain
+
+import "time"
+
+// rosterRange returns the first date of the roster, and the day after the last date of the roster
+func (a *Application) rosterRange() (time.Time, time.Time) {
+	from, to := a.entries[0].Date, a.entries[0].Date
+
+	for _, entry := range a.entries {
+		if entry.Date.Before(from) {
+			from = entry.Date
+		}
+
+		if entry.Date.After(to) {
+			to = entry.Date
+		}
+	}
+
+	return dateToTime(from), dateToTime(to).Add(24 * time.Hour)
+}
+
+// rosterDates returns the dates that are in the selected sheets and dates of the roster. Dates between them that are
+// not in the roster, such as the months of sheets that were left out, are not part of it.
+func (a *Application) rosterDates() map[time.Time]bool {
+	dates := make(map[time.Time]bool)
+
+	for _, entry := range a.entries {
+		dates[dateToTime(entry.Date)] = true
+	}
+
+	return dates
+}
+
+// synchronizeEvents compares the events converted from the roster with the events the importer created before on the
+// dates of the roster. Shifts that changed are updated, and shifts that are no longer in the roster are deleted.
+// Events that were not created by the importer are never changed.
+func (a *Application) synchronizeEvents() {
+	dates := a.rosterDates()
+
+	managed := make(map[time.Time][]*ScheduleEvent)
+	unmanaged := make(map[eventKey]bool)
+
+	for _, event := range a.eventsInCalendar {
+		date := event.Date()
+
+		if !event.Managed {
+			unmanaged[event.key()] = true
+		} else if dates[date] {
+			managed[date] = append(managed[date], event)
+		}
+	}
+
+	// first take out the events that are already in the calendar as they are, so that they aren't used for updates
+	changed := []*ScheduleEvent{}
+
+	for _, event := range a.eventsForCalendar {
+		date := event.Date()
+		existing := managed[date]
+		found := false
+
+		for i, e := range existing {
+			if e.key() == event.key() || e.sameShift(event) {
+				managed[date] = append(existing[:i:i], existing[i+1:]...)
+				found = true
+				break
+			}
+		}
+
+		if !found && !unmanaged[event.key()] {
+			changed = append(changed, event)
+		}
+	}
+
+	// then update the events that were imported on the same date, or create new ones when there are none
+	a.newEventsForCalendar = []*ScheduleEvent{}
+
+	for _, event := range changed {
+		date := event.Date()
+
+		if existing := managed[date]; len(existing) > 0 {
+			updated := *event
+			updated.Id = existing[0].Id
+			updated.Managed = true
+
+			a.eventsToUpdate = append(a.eventsToUpdate, &updated)
+			managed[date] = existing[1:]
+		} else {
+			a.newEventsForCalendar = append(a.newEventsForCalendar, event)
+		}
+	}
+
+	// whatever is left was imported before, but is no longer in the roster
+	for _, event := range a.eventsInCalendar {
+		for _, e := range managed[event.Date()] {
+			if e == event {
+				a.eventsToDelete = append(a.eventsToDelete, event)
+			}
+		}
+	}
+
+	a.uistate.EventsNotAlreadyInCalendar = a.newEventsForCalendar
+	a.uistate.EventsToUpdate = a.eventsToUpdate
+	a.uistate.EventsToDelete = a.eventsToDelete
+}
@@ -0,0 +1,202 @@
+package domain
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"rooster-importer/pkg/excelreader"
+	"sort"
+	"strings"
+	"unicode"
+)
+
+// TeamSchedule is the converted roster of one person in the selected file, for exporting the rosters of a whole team.
+type TeamSchedule struct {
+	Name   string
+	File   string
+	Events []*ScheduleEvent
+	// Unmapped are the codes in the roster that are not in the mapping table, and were converted to the default shift
+	Unmapped []string
+	// Err is set when the file of the person could not be written
+	Err error
+}
+
+// Summary describes the exported roster in a single line.
+func (s *TeamSchedule) Summary() string {
+	if s.Err != nil {
+		return fmt.Sprintf("%s: not saved, %s", s.Name, s.Err)
+	}
+
+	summary := fmt.Sprintf("%s: %d events in %s", s.Name, len(s.Events), s.File)
+
+	if len(s.Unmapped) > 0 {
+		summary += fmt.Sprintf(", unmapped codes: %s", strings.Join(s.Unmapped, ", "))
+	}
+
+	return summary
+}
+
+// ExistingFilesError is returned when exporting the rosters of a team would overwrite files, which is only done when
+// the user agrees to it.
+type ExistingFilesError struct {
+	Dir   string
+	Files []string
+}
+
+func (e *ExistingFilesError) Error() string {
+	return fmt.Sprintf("%d files already exist in %s: %s", len(e.Files), e.Dir, strings.Join(e.Files, ", "))
+}
+
+// ExportTeamIcsAction converts the roster of everyone in the selected sheets and dates of the file, and writes an
+// iCalendar file for every person to dir. The files are named after the people. Existing files are only overwritten
+// with overwrite, otherwise an *ExistingFilesError lists them and nothing is written. A file that cannot be written
+// doesn't stop the files of the other people from being written.
+func ExportTeamIcsAction(dir string, overwrite bool) Action {
+	return func(a *Application) {
+		if a.book == nil {
+			a.guistuff <- errors.New("no roster to export, select a roster first")
+			return
+		}
+
+		team, err := excelreader.FindTeamEntriesInLayout(a.sheetBook(), a.layout)
+
+		if err != nil {
+			var noEntriesError *excelreader.NoEntriesFoundError
+
+			if !errors.As(err, &noEntriesError) {
+				a.guistuff <- err
+				return
+			}
+
+			a.guistuff <- Information(err.Error())
+		}
+
+		if len(team) == 0 {
+			a.guistuff <- errors.New("no people found in the roster")
+			return
+		}
+
+		names := make([]string, 0, len(team))
+
+		for name := range team {
+			names = append(names, name)
+		}
+
+		sort.Strings(names)
+
+		schedules := []TeamSchedule{}
+		files := make(map[string]bool)
+		existing := []string{}
+
+		for _, name := range names {
+			schedule := a.mapping.teamSchedule(name, a.inDateRange(team[name]))
+			schedule.File = uniqueFileName(teamFileName(name), files)
+			schedules = append(schedules, schedule)
+
+			if _, err := os.Stat(filepath.Join(dir, schedule.File)); err == nil {
+				existing = append(existing, schedule.File)
+			}
+		}
+
+		if len(existing) > 0 && !overwrite {
+			a.guistuff <- &ExistingFilesError{Dir: dir, Files: existing}
+			return
+		}
+
+		saved := 0
+		failed := []string{}
+		summary := strings.Builder{}
+
+		for i := range schedules {
+			schedule := &schedules[i]
+			schedule.Err = writeTeamFile(filepath.Join(dir, schedule.File), schedule)
+
+			if schedule.Err != nil {
+				failed = append(failed, schedule.Summary())
+			} else {
+				saved += 1
+			}
+
+			summary.WriteString(schedule.Summary())
+			summary.WriteString("\n")
+		}
+
+		a.uistate.Team = schedules
+
+		if saved > 0 {
+			a.guistuff <- Information(fmt.Sprintf("Saved the rosters of %d people to %s:\n%s", saved, dir, summary.String()))
+		}
+
+		if len(failed) > 0 {
+			a.guistuff <- fmt.Errorf("cannot save the rosters of %d people: %s", len(failed), strings.Join(failed, "; "))
+		}
+
+		a.guistuff <- NewState(a.uistate)
+	}
+}
+
+// writeTeamFile writes the roster of a person to path, and removes what was written when that fails halfway.
+func writeTeamFile(path string, schedule *TeamSchedule) error {
+	file, err := os.Create(path)
+
+	if err != nil {
+		return err
+	}
+
+	if err := writeIcs(file, schedule.Events, schedule.Name); err != nil {
+		os.Remove(path)
+		return err
+	}
+
+	return nil
+}
+
+// teamSchedule converts the roster of one person, and collects the codes that are not in the mapping table.
+func (m *ShiftMapping) teamSchedule(name string, entries []excelreader.ScheduleEntry) TeamSchedule {
+	converted := m.convert(entries)
+	unmapped := []string{}
+	seen := make(map[string]bool)
+
+	for _, warning := range converted.warnings {
+		if !seen[warning.Code] {
+			seen[warning.Code] = true
+			unmapped = append(unmapped, warning.Code)
+		}
+	}
+
+	sort.Strings(unmapped)
+
+	return TeamSchedule{Name: name, Events: converted.events, Unmapped: unmapped}
+}
+
+// teamFileName turns a name into the name of an iCalendar file, e.g. "Vries, Jan de" into "vries-jan-de.ics".
+func teamFileName(name string) string {
+	return nameSlug(name) + ".ics"
+}
+
+// nameSlug turns a name into lowercase words joined by dashes, e.g. "Vries, Jan de" into "vries-jan-de".
+func nameSlug(name string) string {
+	words := strings.FieldsFunc(strings.ToLower(name), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	})
+
+	if len(words) == 0 {
+		words = []string{"rooster"}
+	}
+
+	return strings.Join(words, "-")
+}
+
+// uniqueFileName numbers a file name when it is used already.
+func uniqueFileName(name string, used map[string]bool) string {
+	unique := name
+
+	for i := 2; used[unique]; i++ {
+		unique = fmt.Sprintf("%s-%d.ics", strings.TrimSuffix(name, ".ics"), i)
+	}
+
+	used[unique] = true
+
+	return unique
+}
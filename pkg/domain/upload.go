@@ -0,0 +1,176 @@
+package domain
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+)
+
+// DefaultConcurrency is the number of events that are written to the calendar at the same time
+const DefaultConcurrency = 4
+
+// change is a single change to write to the calendar. It returns the id of the event in the calendar, also when the
+// write fails.
+type change struct {
+	kind  ChangeKind
+	event *ScheduleEvent
+	write func(ctx context.Context) (string, error)
+}
+
+type changeResult struct {
+	EventResult
+	id string
+	// interrupted changes were not written because the import was canceled
+	interrupted bool
+	// uncertain creates were canceled while they were sent, so the server may have created the event anyway
+	uncertain bool
+}
+
+// writeChanges writes the changes using a bounded number of workers. Progress is reported in the order in which the
+// changes are done, and the results are returned in the order of the changes. Changes that were not written because
+// ctx was canceled are left out.
+func (a *Application) writeChanges(ctx context.Context, changes []change) ([]changeResult, bool) {
+	workers := a.concurrency
+
+	if workers < 1 {
+		workers = 1
+	}
+
+	results := make([]changeResult, len(changes))
+	jobs := make(chan int)
+	finished := make(chan int)
+	wg := sync.WaitGroup{}
+
+	for w := 0; w < workers; w++ {
+		wg.Add(1)
+
+		go func() {
+			defer wg.Done()
+
+			for i := range jobs {
+				results[i] = writeChange(ctx, changes[i])
+				finished <- i
+			}
+		}()
+	}
+
+	go func() {
+		defer close(jobs)
+
+		for i := range changes {
+			select {
+			case jobs <- i:
+			case <-ctx.Done():
+				for ; i < len(changes); i++ {
+					results[i] = changeResult{interrupted: true}
+				}
+				return
+			}
+		}
+	}()
+
+	go func() {
+		wg.Wait()
+		close(finished)
+	}()
+
+	done := 0
+
+	for i := range finished {
+		if !results[i].interrupted {
+			done += 1
+			a.guistuff <- Progress{Done: done, Total: len(changes)}
+		}
+	}
+
+	written := []changeResult{}
+	canceled := false
+
+	for _, result := range results {
+		if result.interrupted {
+			canceled = true
+		} else {
+			written = append(written, result)
+		}
+	}
+
+	return written, canceled
+}
+
+func writeChange(ctx context.Context, c change) changeResult {
+	if ctx.Err() != nil {
+		return changeResult{interrupted: true}
+	}
+
+	id, err := c.write(ctx)
+
+	if errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded) {
+		if c.kind != ChangeCreate || id == "" {
+			return changeResult{interrupted: true}
+		}
+
+		return changeResult{
+			EventResult: EventResult{Event: c.event, Change: c.kind, Err: fmt.Errorf("canceled while creating, the event may exist in the calendar: %w", err)},
+			id:          id,
+			uncertain:   true,
+		}
+	}
+
+	return changeResult{
+		EventResult: EventResult{Event: c.event, Change: c.kind, Err: err},
+		id:          id,
+	}
+}
+
+// applyWrittenChanges updates the events in the calendar with the changes that were written, for when the calendar
+// cannot be listed again.
+func (a *Application) applyWrittenChanges(results []changeResult) {
+	gone := []string{}
+
+	for _, result := range results {
+		if result.Err != nil {
+			continue
+		}
+
+		switch result.Change {
+		case ChangeCreate:
+			event := *result.Event
+			event.Id = result.id
+			event.Managed = true
+			a.eventsInCalendar = append(a.eventsInCalendar, &event)
+		case ChangeUpdate:
+			for i, event := range a.eventsInCalendar {
+				if event.Id == result.id {
+					updated := *result.Event
+					updated.Managed = true
+					a.eventsInCalendar[i] = &updated
+				}
+			}
+		case ChangeDelete:
+			gone = append(gone, result.id)
+		}
+	}
+
+	a.forgetEventsInCalendar(gone)
+}
+
+// forgetEventsInCalendar leaves the events with the given ids out of the events in the calendar.
+func (a *Application) forgetEventsInCalendar(ids []string) {
+	remaining := []*ScheduleEvent{}
+
+	for _, event := range a.eventsInCalendar {
+		if !contains(ids, event.Id) {
+			remaining = append(remaining, event)
+		}
+	}
+
+	a.eventsInCalendar = remaining
+}
+
+// SetConcurrencyAction sets the number of events that are written to the calendar at the same time.
+func SetConcurrencyAction(workers int) Action {
+	return func(a *Application) {
+		a.concurrency = workers
+	}
+}
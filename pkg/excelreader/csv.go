@@ -0,0 +1,86 @@
+package excelreader
+
+import (
+	"bytes"
+	"encoding/csv"
+	"fmt"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// csvWorkbook is a comma, semicolon or tab separated file, which has a single sheet and no cell formats.
+type csvWorkbook struct {
+	sheet string
+	rows  [][]string
+}
+
+var utf8Bom = []byte("\xEF\xBB\xBF")
+
+func readCsv(contents []byte, filename string, separator rune) (Workbook, error) {
+	contents = bytes.TrimPrefix(contents, utf8Bom)
+
+	if separator == 0 {
+		separator = guessSeparator(contents)
+	}
+
+	reader := csv.NewReader(bytes.NewReader(contents))
+	reader.Comma = separator
+	reader.FieldsPerRecord = -1
+	reader.LazyQuotes = true
+
+	rows, err := reader.ReadAll()
+
+	if err != nil {
+		return nil, fmt.Errorf("cannot read %s as a separated values file: %w", filepath.Base(filename), err)
+	}
+
+	sheet := strings.TrimSuffix(filepath.Base(filename), filepath.Ext(filename))
+
+	if sheet == "" || sheet == "." {
+		sheet = "Sheet1"
+	}
+
+	return &csvWorkbook{sheet: sheet, rows: rows}, nil
+}
+
+// guessSeparator picks the separator that occurs most in the first lines. Spreadsheet applications with a Dutch locale
+// use semicolons, because the comma is the decimal separator.
+func guessSeparator(contents []byte) rune {
+	lines := bytes.SplitN(contents, []byte("\n"), 6)
+	best, bestCount := ',', 0
+
+	for _, separator := range []rune{',', ';', '\t'} {
+		count := 0
+
+		for _, line := range lines {
+			count += bytes.Count(line, []byte(string(separator)))
+		}
+
+		if count > bestCount {
+			best, bestCount = separator, count
+		}
+	}
+
+	return best
+}
+
+func (w *csvWorkbook) Sheets() []string {
+	return []string{w.sheet}
+}
+
+func (w *csvWorkbook) Rows(sheet string) ([][]string, error) {
+	if sheet != w.sheet {
+		return nil, fmt.Errorf("sheet %s does not exist", sheet)
+	}
+
+	return w.rows, nil
+}
+
+func (w *csvWorkbook) Date(sheet string, col, row int, content string) (time.Time, bool) {
+	return time.Time{}, false
+}
+
+func (w *csvWorkbook) MergedCells(sheet string) ([]CellRange, error) {
+	return nil, nil
+}
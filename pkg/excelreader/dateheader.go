@@ -0,0 +1,89 @@
+package excelreader
+
+import (
+	"strconv"
+	"strings"
+	"time"
+)
+
+var headerMonths = map[string]time.Month{
+	"jan": time.January, "januari": time.January, "january": time.January,
+	"feb": time.February, "februari": time.February, "february": time.February,
+	"mrt": time.March, "maa": time.March, "mar": time.March, "maart": time.March, "march": time.March,
+	"apr": time.April, "april": time.April,
+	"mei": time.May, "may": time.May,
+	"jun": time.June, "juni": time.June, "june": time.June,
+	"jul": time.July, "juli": time.July, "july": time.July,
+	"aug": time.August, "augustus": time.August, "august": time.August,
+	"sep": time.September, "sept": time.September, "september": time.September,
+	"okt": time.October, "oct": time.October, "oktober": time.October, "october": time.October,
+	"nov": time.November, "november": time.November,
+	"dec": time.December, "december": time.December,
+}
+
+var headerWeekdays = map[string]bool{
+	"ma": true, "di": true, "wo": true, "do": true, "vr": true, "za": true, "zo": true,
+	"maandag": true, "dinsdag": true, "woensdag": true, "donderdag": true, "vrijdag": true, "zaterdag": true, "zondag": true,
+	"mon": true, "tue": true, "wed": true, "thu": true, "fri": true, "sat": true, "sun": true,
+	"monday": true, "tuesday": true, "wednesday": true, "thursday": true, "friday": true, "saturday": true, "sunday": true,
+}
+
+// parseDateHeader parses dates written out in Dutch or English, as they appear in the headers of rosters: "ma 3 jan",
+// "di 4-mrt", "wo 5 okt 2024", "zo 7-1" or "Mon Jan 3". The weekday is optional and not checked. Without a year, the
+// date is in year 0, and the year is inferred from the other dates in the row.
+func parseDateHeader(cell string) (time.Time, bool) {
+	fields := strings.FieldsFunc(strings.ToLower(cell), func(r rune) bool {
+		return r == ' ' || r == '-' || r == '/' || r == '.' || r == ',' || r == '\t'
+	})
+
+	weekday := len(fields) > 0 && headerWeekdays[fields[0]]
+
+	if weekday {
+		fields = fields[1:]
+	}
+
+	if len(fields) < 2 || len(fields) > 3 {
+		return time.Time{}, false
+	}
+
+	day, err := strconv.Atoi(fields[0])
+	month, ok := headerMonths[fields[1]]
+
+	if number, numberErr := strconv.Atoi(fields[1]); weekday && !ok && numberErr == nil && number >= 1 && number <= 12 {
+		// after a weekday, the month can be a number as well: "zo 7-1"
+		month, ok = time.Month(number), true
+	}
+
+	if err != nil || !ok {
+		// English order, the month before the day
+		day, err = strconv.Atoi(fields[1])
+		month, ok = headerMonths[fields[0]]
+	}
+
+	if err != nil || !ok || day < 1 || day > 31 {
+		return time.Time{}, false
+	}
+
+	year := 0
+
+	if len(fields) == 3 {
+		year, err = strconv.Atoi(fields[2])
+
+		if err != nil || year < 0 {
+			return time.Time{}, false
+		}
+
+		if len(fields[2]) == 2 {
+			year += 2000
+		}
+	}
+
+	date := time.Date(year, month, day, 0, 0, 0, 0, time.UTC)
+
+	// reject days that don't exist in the month, such as the 31st of April
+	if date.Day() != day {
+		return time.Time{}, false
+	}
+
+	return date, true
+}
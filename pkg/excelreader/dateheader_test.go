@@ -0,0 +1,53 @@
+package excelreader_test
+
+import (
+	"rooster-importer/pkg/excelreader"
+	"strings"
+	"testing"
+)
+
+func TestDutchDateHeaders(t *testing.T) {
+	headers := []string{
+		"Naam", "ma 1 jan", "di 2-jan", "wo 3 jan 2024", "do 4 januari", "vr 5 jan.", "Za 6 Jan", "zo 7-1-2024",
+		"maandag 8 jan", "Tue Jan 9", "wed 10 january", "11 jan 24", "vrijdag 12 jan", "13 jan", "14-jan",
+	}
+	contents := strings.Join(headers, ";") + "\n" +
+		"Firstname Lastname;" + strings.Join(strings.Fields(rosterShifts), ";") + "\n"
+
+	book, err := excelreader.ReadWorkbook(strings.NewReader(contents), "rooster.csv")
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	entries, err := excelreader.FindEntries(book, "Firstname")
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	checkEntries(t, entries)
+}
+
+func TestInvalidDateHeaders(t *testing.T) {
+	headers := []string{"Naam", "ma 31 apr", "di 1 mei", "wo 2 mei", "do 3 mei", "vr 4 mei", "za 5 mei", "zo 6 mei",
+		"ma 7 mei", "di 8 mei", "wo 9 mei", "do 10 mei"}
+	contents := strings.Join(headers, ";") + "\n" +
+		"Firstname Lastname;x;a;a;a;a;a;a;a;a;a;a\n"
+
+	book, err := excelreader.ReadWorkbook(strings.NewReader(contents), "rooster.csv")
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	entries, err := excelreader.FindEntries(book, "Firstname")
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(entries) != 10 || entries[0].Date.Day() != 1 {
+		t.Errorf("expected the 31st of April to be skipped, got %v", entries)
+	}
+}
@@ -0,0 +1,169 @@
+package excelreader
+
+import (
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// headerPeriod is a week or a month, as named in a header above a row of day numbers. Year 0 means that the header
+// didn't mention the year.
+type headerPeriod struct {
+	year  int
+	week  int
+	month time.Month
+}
+
+var (
+	weekHeader  = regexp.MustCompile(`^(?:week|wk|w)\s*\.?\s*(\d{1,2})(?:\D+(\d{4}))?$`)
+	monthHeader = regexp.MustCompile(`^([a-z]+)\.?(?:\s*[-/ ]\s*'?(\d{4}|\d{2}))?$`)
+	yearPattern = regexp.MustCompile(`\b(?:19|20)\d{2}\b`)
+)
+
+func parseHeaderPeriod(cell string) (headerPeriod, bool) {
+	cell = strings.ToLower(strings.TrimSpace(cell))
+
+	if match := weekHeader.FindStringSubmatch(cell); match != nil {
+		week, _ := strconv.Atoi(match[1])
+		year, _ := strconv.Atoi(match[2])
+
+		return headerPeriod{year: year, week: week}, week >= 1 && week <= 53
+	}
+
+	if match := monthHeader.FindStringSubmatch(cell); match != nil {
+		month, ok := headerMonths[match[1]]
+		year, _ := strconv.Atoi(match[2])
+
+		if len(match[2]) == 2 {
+			year += 2000
+		}
+
+		return headerPeriod{year: year, month: month}, ok
+	}
+
+	return headerPeriod{}, false
+}
+
+// day returns the date of a day number within the period. Weeks are ISO weeks, which start on monday.
+func (p headerPeriod) day(number int) (time.Time, bool) {
+	if p.week == 0 {
+		date := time.Date(p.year, p.month, number, 0, 0, 0, 0, time.UTC)
+
+		return date, date.Day() == number
+	}
+
+	// the 4th of January is always in week 1
+	jan4 := time.Date(p.year, time.January, 4, 0, 0, 0, 0, time.UTC)
+	daysSinceMonday := (int(jan4.Weekday()) + 6) % 7
+	monday := jan4.AddDate(0, 0, (p.week-1)*7-daysSinceMonday)
+
+	for i := 0; i < 7; i++ {
+		if date := monday.AddDate(0, 0, i); date.Day() == number {
+			return date, true
+		}
+	}
+
+	return time.Time{}, false
+}
+
+// headerPeriods maps the columns of a header row to the week or month that they name. A header counts for all columns
+// of its merged cell, or when it isn't merged, for the columns up to the next header. Weeks without a year are in the
+// year that the sheet name mentions, or else this year, and go into the next year when the week numbers start over.
+func headerPeriods(header []string, headeridx int, merged []CellRange, sheet string, width int) map[int]headerPeriod {
+	periods := make(map[int]headerPeriod)
+
+	weekYear := time.Now().Year()
+
+	if match := yearPattern.FindString(sheet); match != "" {
+		weekYear, _ = strconv.Atoi(match)
+	}
+
+	lastWeek := 0
+
+	for col := 0; col < len(header); col++ {
+		if strings.TrimSpace(header[col]) == "" {
+			continue
+		}
+
+		period, ok := parseHeaderPeriod(header[col])
+
+		if !ok {
+			continue
+		}
+
+		if period.week != 0 {
+			if period.year != 0 {
+				weekYear = period.year
+			} else if period.week < lastWeek {
+				weekYear += 1
+			}
+
+			period.year = weekYear
+			lastWeek = period.week
+		}
+
+		end := col
+
+		if span, ok := mergedAt(merged, col, headeridx); ok {
+			end = span.ToCol
+		} else {
+			for end+1 < width && (end+1 >= len(header) || strings.TrimSpace(header[end+1]) == "") {
+				end += 1
+			}
+		}
+
+		for c := col; c <= end; c++ {
+			periods[c] = period
+		}
+	}
+
+	return periods
+}
+
+func mergedAt(merged []CellRange, col, row int) (CellRange, bool) {
+	for _, span := range merged {
+		if span.FromCol == col && span.FromRow <= row && row <= span.ToRow {
+			return span, true
+		}
+	}
+
+	return CellRange{}, false
+}
+
+// findDayNumberRow finds the dates of a row with day numbers (1, 2, 3...), using the row above it that names the weeks
+// ("Week 12") or months ("januari 2024") of the days, often in cells that are merged over the days.
+func findDayNumberRow(row []string, header []string, headeridx int, merged []CellRange, sheet string) (map[int]time.Time, bool) {
+	if header == nil {
+		return nil, false
+	}
+
+	periods := headerPeriods(header, headeridx, merged, sheet, len(row))
+
+	if len(periods) == 0 {
+		return nil, false
+	}
+
+	datemap := make(map[int]time.Time)
+	datelocations := []int{}
+
+	for x, cell := range row {
+		number, err := strconv.Atoi(strings.TrimSpace(cell))
+		period, ok := periods[x]
+
+		if err != nil || !ok {
+			continue
+		}
+
+		if date, ok := period.day(number); ok {
+			datemap[x] = date
+			datelocations = append(datelocations, x)
+		}
+	}
+
+	if !validDateRow(datemap, datelocations) {
+		return nil, false
+	}
+
+	return datemap, true
+}
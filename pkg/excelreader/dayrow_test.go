@@ -0,0 +1,107 @@
+package excelreader_test
+
+import (
+	"rooster-importer/pkg/excelreader"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/xuri/excelize/v2"
+)
+
+func TestMergedWeekHeaders(t *testing.T) {
+	file := excelize.NewFile()
+	file.SetSheetName("Sheet1", "Rooster 2023")
+	sheet := "Rooster 2023"
+
+	// week 52 of 2023 runs from the 25th to the 31st of December, week 1 of 2024 from the 1st to the 7th of January
+	file.SetCellValue(sheet, "B1", "Week 52")
+	file.MergeCell(sheet, "B1", "H1")
+	file.SetCellValue(sheet, "J1", "Week 1")
+	file.MergeCell(sheet, "J1", "P1")
+
+	days := []int{25, 26, 27, 28, 29, 30, 31, 0, 1, 2, 3, 4, 5, 6, 7}
+	shifts := strings.Fields("d t a n x x x uren d d d d d x x")
+
+	file.SetCellValue(sheet, "A3", "Firstname Lastname")
+
+	for i, day := range days {
+		dayCell, _ := excelize.CoordinatesToCellName(i+2, 2)
+		entryCell, _ := excelize.CoordinatesToCellName(i+2, 3)
+
+		if day == 0 {
+			file.SetCellValue(sheet, dayCell, "Totaal")
+		} else {
+			file.SetCellValue(sheet, dayCell, day)
+		}
+
+		file.SetCellValue(sheet, entryCell, shifts[i])
+	}
+
+	buffer, err := file.WriteToBuffer()
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	book, err := excelreader.ReadWorkbook(buffer, "rooster.xlsx")
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	entries, err := excelreader.FindEntries(book, "Firstname")
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(entries) != 14 {
+		t.Fatalf("expected 14 entries, got %d: %v", len(entries), entries)
+	}
+
+	start := time.Date(2023, time.December, 25, 0, 0, 0, 0, time.UTC)
+
+	for i, entry := range entries {
+		if expected := start.AddDate(0, 0, i); !entry.Date.Equal(expected) {
+			t.Errorf("entry %d: expected %s, got %s", i, expected.Format(time.DateOnly), entry.Date.Format(time.DateOnly))
+		}
+	}
+
+	if entries[7].Shift != "d" {
+		t.Errorf("expected the week total column to be skipped, got %s on the 1st of January", entries[7].Shift)
+	}
+}
+
+func TestMonthHeader(t *testing.T) {
+	// a month header that isn't merged counts for the columns up to the next header
+	header := []string{"", "januari 2024"}
+	days := []string{"Naam"}
+
+	for day := 1; day <= 14; day++ {
+		days = append(days, strconv.Itoa(day))
+
+		if day > 1 {
+			header = append(header, "")
+		}
+	}
+
+	contents := strings.Join(header, ";") + "\n" +
+		strings.Join(days, ";") + "\n" +
+		"Firstname Lastname;" + strings.Join(strings.Fields(rosterShifts), ";") + "\n"
+
+	book, err := excelreader.ReadWorkbook(strings.NewReader(contents), "rooster.csv")
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	entries, err := excelreader.FindEntries(book, "Firstname")
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	checkEntries(t, entries)
+}
@@ -0,0 +1,211 @@
+package excelreader
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+	"strings"
+	"time"
+)
+
+// Orientation tells which way a roster runs.
+type Orientation string
+
+const (
+	// OrientationAuto tries rows of dates first, and otherwise a column of dates
+	OrientationAuto Orientation = "auto"
+	// OrientationHorizontal has a row of dates and a name in the first column of each row
+	OrientationHorizontal Orientation = "horizontal"
+	// OrientationVertical has a column of dates and the names in the first row
+	OrientationVertical Orientation = "vertical"
+)
+
+func (o Orientation) Valid() bool {
+	switch o {
+	case "", OrientationAuto, OrientationHorizontal, OrientationVertical:
+		return true
+	}
+
+	return false
+}
+
+// Layout describes how the rosters in a workbook are laid out. The zero Layout detects the layout by itself, for every
+// sheet of the workbook.
+//
+// Rows and columns are numbered from 1, as in Excel, and are in the orientation of the roster: in a vertical roster the
+// date row is the column with the dates, and the name column is the row with the names.
+type Layout struct {
+	Name string `json:"name"`
+	// SheetPattern is a regular expression that selects the sheets with rosters, all sheets are read when it is empty
+	SheetPattern string      `json:"sheetPattern,omitempty"`
+	Orientation  Orientation `json:"orientation,omitempty"`
+	// DateRow is the row with the dates, which is detected when it is 0
+	DateRow int `json:"dateRow,omitempty"`
+	// NameColumn is the column with the names, the first column when it is 0
+	NameColumn int `json:"nameColumn,omitempty"`
+	// FirstDataColumn is the first column with dates and shifts, dates in the columns before it are ignored
+	FirstDataColumn int `json:"firstDataColumn,omitempty"`
+	// HeaderOffset is the number of rows at the top of a sheet that are skipped
+	HeaderOffset int `json:"headerOffset,omitempty"`
+}
+
+// Validate checks the layout for mistakes, and returns all of them.
+func (l *Layout) Validate() []string {
+	problems := []string{}
+
+	if !l.Orientation.Valid() {
+		problems = append(problems, fmt.Sprintf("unknown orientation %q, use %s, %s or %s", l.Orientation,
+			OrientationAuto, OrientationHorizontal, OrientationVertical))
+	}
+
+	if _, err := regexp.Compile(l.SheetPattern); err != nil {
+		problems = append(problems, fmt.Sprintf("invalid sheet pattern: %s", err))
+	}
+
+	if l.DateRow < 0 || l.NameColumn < 0 || l.FirstDataColumn < 0 || l.HeaderOffset < 0 {
+		problems = append(problems, "rows and columns cannot be negative")
+	}
+
+	if l.DateRow > 0 && l.DateRow <= l.HeaderOffset {
+		problems = append(problems, fmt.Sprintf("date row %d is skipped by the header offset of %d rows", l.DateRow, l.HeaderOffset))
+	}
+
+	return problems
+}
+
+// FindEntriesInLayout reads the roster of name from the sheets of a workbook that is laid out as described.
+func FindEntriesInLayout(book Workbook, name string, layout Layout) ([]ScheduleEntry, error) {
+	if problems := layout.Validate(); len(problems) > 0 {
+		return nil, fmt.Errorf("invalid layout %s: %s", layout.Name, strings.Join(problems, "; "))
+	}
+
+	matches, err := nameMatcher(book, name, layout)
+
+	if err != nil {
+		return nil, err
+	}
+
+	pattern := regexp.MustCompile(layout.SheetPattern)
+
+	return findEntries(book, pattern, func(sheet string) ([]ScheduleEntry, error) {
+		switch layout.Orientation {
+		case OrientationHorizontal:
+			return processSheet(book, sheet, matches, layout)
+		case OrientationVertical:
+			return processSheet(transpose(book), sheet, matches, layout)
+		}
+
+		entries, err := processSheet(book, sheet, matches, layout)
+
+		if errors.Is(err, NoEntriesInSheet) || errors.Is(err, NotAScheduleSheet) {
+			if transposed, transposedErr := processSheet(transpose(book), sheet, matches, layout); transposedErr == nil {
+				return transposed, nil
+			}
+		}
+
+		return entries, err
+	})
+}
+
+// nameMatcher decides which rows belong to the user with the given name, by matching the name to the people in the
+// workbook. When the name matches nobody, a *NoEntriesFoundError suggests the closest names.
+func nameMatcher(book Workbook, name string, layout Layout) (func(rowName string) bool, error) {
+	sheets, err := FindNamesInLayout(book, layout)
+
+	if err != nil {
+		return nil, err
+	}
+
+	names := []string{}
+
+	for _, sheet := range sheets {
+		names = append(names, sheet.Names...)
+	}
+
+	match, ok, err := MatchName(name, names)
+
+	if err != nil {
+		return nil, err
+	}
+
+	if !ok {
+		return nil, &NoEntriesFoundError{Name: name, Closest: ClosestNames(name, names, 3)}
+	}
+
+	key := strings.Join(nameTokens(match), " ")
+
+	return func(rowName string) bool {
+		return strings.Join(nameTokens(rowName), " ") == key
+	}, nil
+}
+
+// transposed is a workbook of which the rows and columns are swapped, so that a roster with dates down the first
+// column and names across the first row can be read like any other roster.
+type transposed struct {
+	book Workbook
+}
+
+func transpose(book Workbook) Workbook {
+	return &transposed{book: book}
+}
+
+func (t *transposed) Sheets() []string {
+	return t.book.Sheets()
+}
+
+func (t *transposed) Rows(sheet string) ([][]string, error) {
+	rows, err := t.book.Rows(sheet)
+
+	if err != nil {
+		return nil, err
+	}
+
+	width := 0
+
+	for _, row := range rows {
+		if len(row) > width {
+			width = len(row)
+		}
+	}
+
+	columns := make([][]string, width)
+
+	for x := range columns {
+		column := make([]string, len(rows))
+		length := 0
+
+		for y, row := range rows {
+			if x < len(row) {
+				column[y] = row[x]
+
+				if row[x] != "" {
+					length = y + 1
+				}
+			}
+		}
+
+		columns[x] = column[:length]
+	}
+
+	return columns, nil
+}
+
+func (t *transposed) Date(sheet string, col, row int, content string) (time.Time, bool) {
+	return t.book.Date(sheet, row, col, content)
+}
+
+func (t *transposed) MergedCells(sheet string) ([]CellRange, error) {
+	merged, err := t.book.MergedCells(sheet)
+
+	if err != nil {
+		return nil, err
+	}
+
+	swapped := make([]CellRange, len(merged))
+
+	for i, r := range merged {
+		swapped[i] = CellRange{FromCol: r.FromRow, FromRow: r.FromCol, ToCol: r.ToRow, ToRow: r.ToCol}
+	}
+
+	return swapped, nil
+}
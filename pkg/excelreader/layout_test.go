@@ -0,0 +1,57 @@
+package excelreader_test
+
+import (
+	"rooster-importer/pkg/excelreader"
+	"strings"
+	"testing"
+	"time"
+)
+
+// verticalRoster has the dates down the first column, and a column per person
+func verticalRoster() string {
+	lines := []string{"Datum;Someone;Firstname Lastname"}
+
+	for i, shift := range strings.Fields(rosterShifts) {
+		lines = append(lines, rosterStart.AddDate(0, 0, i).Format(time.DateOnly)+";x;"+shift)
+	}
+
+	return strings.Join(lines, "\n") + "\n"
+}
+
+func TestVerticalLayout(t *testing.T) {
+	book, err := excelreader.ReadWorkbook(strings.NewReader(verticalRoster()), "rooster.csv")
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, orientation := range []excelreader.Orientation{"", excelreader.OrientationAuto, excelreader.OrientationVertical} {
+		entries, err := excelreader.FindEntriesInLayout(book, "Firstname", excelreader.Layout{Orientation: orientation})
+
+		if err != nil {
+			t.Fatalf("%q: %v", orientation, err)
+		}
+
+		checkEntries(t, entries)
+	}
+
+	if _, err := excelreader.FindEntriesInLayout(book, "Firstname", excelreader.Layout{Orientation: excelreader.OrientationHorizontal}); err == nil {
+		t.Error("expected no entries when forcing the horizontal orientation")
+	}
+
+	if _, err := excelreader.FindEntriesInLayout(book, "Firstname", excelreader.Layout{Orientation: "diagonal"}); err == nil {
+		t.Error("expected an error for an unknown orientation")
+	}
+}
+
+func TestForcedVerticalLayout(t *testing.T) {
+	book, err := excelreader.ReadWorkbook(strings.NewReader(separatedRoster(";", "2-1-2006")), "rooster.csv")
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := excelreader.FindEntriesInLayout(book, "Firstname", excelreader.Layout{Orientation: excelreader.OrientationVertical}); err == nil {
+		t.Error("expected no entries in a horizontal roster when forcing the vertical orientation")
+	}
+}
@@ -0,0 +1,256 @@
+package excelreader
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"unicode"
+
+	"golang.org/x/text/runes"
+	"golang.org/x/text/transform"
+	"golang.org/x/text/unicode/norm"
+)
+
+// AmbiguousNameError is returned when a name matches several people in a roster equally well.
+type AmbiguousNameError struct {
+	Name       string
+	Candidates []string
+}
+
+func (e *AmbiguousNameError) Error() string {
+	return fmt.Sprintf("%s matches %d people: %s", e.Name, len(e.Candidates), strings.Join(e.Candidates, ", "))
+}
+
+// nameTokens splits a name into lowercase words without accents, so that "Jansen, Piet" and "piet jansen" have the
+// same words, and "Zoë" matches "Zoe".
+func nameTokens(name string) []string {
+	folded, _, err := transform.String(transform.Chain(norm.NFD, runes.Remove(runes.In(unicode.Mn)), norm.NFC), name)
+
+	if err != nil {
+		folded = name
+	}
+
+	return strings.FieldsFunc(strings.ToLower(folded), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	})
+}
+
+// editDistance returns the number of letters that have to be inserted, deleted, replaced or swapped with the next
+// letter to turn a into b.
+func editDistance(a, b []rune) int {
+	rows := make([][]int, len(a)+1)
+
+	for i := range rows {
+		rows[i] = make([]int, len(b)+1)
+		rows[i][0] = i
+	}
+
+	for j := range rows[0] {
+		rows[0][j] = j
+	}
+
+	for i := 1; i <= len(a); i++ {
+		for j := 1; j <= len(b); j++ {
+			cost := 1
+
+			if a[i-1] == b[j-1] {
+				cost = 0
+			}
+
+			rows[i][j] = minimum(rows[i-1][j]+1, rows[i][j-1]+1, rows[i-1][j-1]+cost)
+
+			if i > 1 && j > 1 && a[i-1] == b[j-2] && a[i-2] == b[j-1] {
+				rows[i][j] = minimum(rows[i][j], rows[i-2][j-2]+1)
+			}
+		}
+	}
+
+	return rows[len(a)][len(b)]
+}
+
+func minimum(values ...int) int {
+	smallest := values[0]
+
+	for _, v := range values[1:] {
+		if v < smallest {
+			smallest = v
+		}
+	}
+
+	return smallest
+}
+
+// tokenScore tells how well a word of the searched name matches a word of a name in the roster: 1 for the same word,
+// 0.9 for the start of the word, less for words with typos, and 0 for other words. Short words must not have typos,
+// longer words may have one or two.
+func tokenScore(query, candidate string) float64 {
+	if query == candidate {
+		return 1
+	}
+
+	if strings.HasPrefix(candidate, query) {
+		return 0.9
+	}
+
+	q, c := []rune(query), []rune(candidate)
+	allowed := 0
+
+	switch {
+	case len(q) >= 8:
+		allowed = 2
+	case len(q) >= 4:
+		allowed = 1
+	}
+
+	if distance := editDistance(q, c); distance <= allowed {
+		return 0.8 - 0.1*float64(distance)
+	}
+
+	return 0
+}
+
+// nameScore tells how well a searched name matches a name in the roster. Every word of the searched name has to match
+// a word of the name, in any order. A name that is exactly the same scores highest.
+func nameScore(query, candidate []string) float64 {
+	if len(query) == 0 {
+		return 0
+	}
+
+	if strings.Join(query, " ") == strings.Join(candidate, " ") {
+		return 2
+	}
+
+	total := 0.0
+
+	for _, q := range query {
+		best := 0.0
+
+		for _, c := range candidate {
+			if score := tokenScore(q, c); score > best {
+				best = score
+			}
+		}
+
+		if best == 0 {
+			return 0
+		}
+
+		total += best
+	}
+
+	return total / float64(len(query))
+}
+
+// similarity tells how much a name resembles a name in the roster, from 0 to 1, also when the words don't match well
+// enough for nameScore.
+func similarity(query, candidate []string) float64 {
+	if len(query) == 0 {
+		return 0
+	}
+
+	total := 0.0
+
+	for _, q := range query {
+		best := 0.0
+
+		for _, c := range candidate {
+			q, c := []rune(q), []rune(c)
+			longest := len(q)
+
+			if len(c) > longest {
+				longest = len(c)
+			}
+
+			if score := 1 - float64(editDistance(q, c))/float64(longest); score > best {
+				best = score
+			}
+		}
+
+		total += best
+	}
+
+	return total / float64(len(query))
+}
+
+// ClosestNames returns at most count names in the roster that resemble name most, best first, to suggest when name
+// matches nobody. Names that have nothing in common with name are left out.
+func ClosestNames(name string, names []string, count int) []string {
+	query := nameTokens(name)
+
+	type scored struct {
+		name  string
+		score float64
+	}
+
+	candidates := []scored{}
+	seen := make(map[string]bool)
+
+	for _, candidate := range names {
+		tokens := nameTokens(candidate)
+		key := strings.Join(tokens, " ")
+
+		if seen[key] {
+			continue
+		}
+
+		seen[key] = true
+
+		if score := similarity(query, tokens); score > 0.3 {
+			candidates = append(candidates, scored{name: candidate, score: score})
+		}
+	}
+
+	sort.SliceStable(candidates, func(i, j int) bool {
+		return candidates[i].score > candidates[j].score
+	})
+
+	closest := []string{}
+
+	for i := 0; i < len(candidates) && i < count; i++ {
+		closest = append(closest, candidates[i].name)
+	}
+
+	return closest
+}
+
+// MatchName finds the name that the user meant among the names in a roster. Case, accents, punctuation and the order
+// of the words don't matter, and small typos are forgiven. When several names match equally well, an
+// *AmbiguousNameError lists them. When no name matches, ok is false.
+func MatchName(name string, names []string) (match string, ok bool, err error) {
+	query := nameTokens(name)
+	best := 0.0
+	candidates := []string{}
+	seen := make(map[string]bool)
+
+	for _, candidate := range names {
+		tokens := nameTokens(candidate)
+		key := strings.Join(tokens, " ")
+
+		if seen[key] {
+			// the same person in another sheet
+			continue
+		}
+
+		seen[key] = true
+
+		score := nameScore(query, tokens)
+
+		switch {
+		case score == 0 || score < best:
+		case score > best:
+			best = score
+			candidates = []string{candidate}
+		default:
+			candidates = append(candidates, candidate)
+		}
+	}
+
+	switch len(candidates) {
+	case 0:
+		return "", false, nil
+	case 1:
+		return candidates[0], true, nil
+	default:
+		return "", false, &AmbiguousNameError{Name: name, Candidates: candidates}
+	}
+}
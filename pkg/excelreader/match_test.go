@@ -0,0 +1,99 @@
+package excelreader_test
+
+import (
+	"errors"
+	"reflect"
+	"rooster-importer/pkg/excelreader"
+	"strings"
+	"testing"
+)
+
+func TestMatchName(t *testing.T) {
+	names := []string{"Jansen, Piet", "Zoë de Vries", "Marie Dubois", "Jan Bakker", "Jan de Vries", "Zoë de Vries", "Jansen"}
+
+	cases := map[string]string{
+		"Piet":          "Jansen, Piet",
+		"Pi":            "Jansen, Piet",
+		"piet jansen":   "Jansen, Piet",
+		"zoe de vries":  "Zoë de Vries",
+		"Vries, Zoë":    "Zoë de Vries",
+		"Zoë de Vreis":  "Zoë de Vries",
+		"Dubios":        "Marie Dubois",
+		"Jan Bakker":    "Jan Bakker",
+		"jan de vries":  "Jan de Vries",
+		"  JAN BAKKER ": "Jan Bakker",
+	}
+
+	for name, expected := range cases {
+		match, ok, err := excelreader.MatchName(name, names)
+
+		if err != nil || !ok || match != expected {
+			t.Errorf("expected %s to match %s, got %q (%t, %v)", name, expected, match, ok, err)
+		}
+	}
+
+	for _, name := range []string{"Kees", "Pa", "Jon de Vries"} {
+		if match, ok, err := excelreader.MatchName(name, names); ok || err != nil {
+			t.Errorf("expected %s to match nobody, got %q (%v)", name, match, err)
+		}
+	}
+}
+
+func TestAmbiguousName(t *testing.T) {
+	names := []string{"Jan de Vries", "Piet Jansen", "Jan Bakker"}
+
+	_, _, err := excelreader.MatchName("jan", names)
+
+	var ambiguous *excelreader.AmbiguousNameError
+
+	if !errors.As(err, &ambiguous) {
+		t.Fatalf("expected an ambiguous name error, got %v", err)
+	}
+
+	if expected := []string{"Jan de Vries", "Jan Bakker"}; !reflect.DeepEqual(ambiguous.Candidates, expected) {
+		t.Errorf("expected candidates %v, got %v", expected, ambiguous.Candidates)
+	}
+}
+
+func TestFindEntriesMatchesName(t *testing.T) {
+	contents := separatedRoster(",", "2006-1-2") +
+		strings.Replace(separatedRoster(",", "2006-1-2"), "Firstname Lastname", "Firstname Otherlastname", 1)
+
+	book, err := excelreader.ReadWorkbook(strings.NewReader(contents), "rooster.csv")
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	entries, err := excelreader.FindEntries(book, "lastname firstname")
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	checkEntries(t, entries)
+
+	var ambiguous *excelreader.AmbiguousNameError
+
+	if _, err := excelreader.FindEntries(book, "Firstname"); !errors.As(err, &ambiguous) {
+		t.Errorf("expected Firstname to be ambiguous, got %v", err)
+	}
+
+	for _, name := range []string{"", "Kees"} {
+		_, err := excelreader.FindEntries(book, name)
+
+		var noEntries *excelreader.NoEntriesFoundError
+
+		if !errors.As(err, &noEntries) {
+			t.Errorf("expected %q to match nobody, got %v", name, err)
+		}
+	}
+
+	_, err = excelreader.FindEntries(book, "Kees Lastname")
+
+	var noEntries *excelreader.NoEntriesFoundError
+
+	if !errors.As(err, &noEntries) || len(noEntries.Closest) == 0 || noEntries.Closest[0] != "Firstname Lastname" {
+		t.Errorf("expected Firstname Lastname to be suggested, got %v", err)
+	}
+}
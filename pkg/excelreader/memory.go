@@ -0,0 +1,75 @@
+package excelreader
+
+import (
+	"fmt"
+	"time"
+)
+
+type cellPosition struct {
+	col, row int
+}
+
+type memorySheet struct {
+	name   string
+	rows   [][]string
+	dates  map[cellPosition]time.Time
+	merged []CellRange
+}
+
+// memoryWorkbook is a workbook that is read into memory completely, for the formats that are parsed here rather than by
+// a library: OpenDocument spreadsheets and old .xls files.
+type memoryWorkbook struct {
+	sheets []*memorySheet
+}
+
+func (w *memoryWorkbook) Sheets() []string {
+	names := make([]string, len(w.sheets))
+
+	for i, sheet := range w.sheets {
+		names[i] = sheet.name
+	}
+
+	return names
+}
+
+func (w *memoryWorkbook) sheet(name string) (*memorySheet, error) {
+	for _, sheet := range w.sheets {
+		if sheet.name == name {
+			return sheet, nil
+		}
+	}
+
+	return nil, fmt.Errorf("sheet %s does not exist", name)
+}
+
+func (w *memoryWorkbook) Rows(name string) ([][]string, error) {
+	sheet, err := w.sheet(name)
+
+	if err != nil {
+		return nil, err
+	}
+
+	return sheet.rows, nil
+}
+
+func (w *memoryWorkbook) Date(name string, col, row int, content string) (time.Time, bool) {
+	sheet, err := w.sheet(name)
+
+	if err != nil {
+		return time.Time{}, false
+	}
+
+	date, ok := sheet.dates[cellPosition{col, row}]
+
+	return date, ok
+}
+
+func (w *memoryWorkbook) MergedCells(name string) ([]CellRange, error) {
+	sheet, err := w.sheet(name)
+
+	if err != nil {
+		return nil, err
+	}
+
+	return sheet.merged, nil
+}
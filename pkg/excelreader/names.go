@@ -0,0 +1,94 @@
+package excelreader
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+// SheetNames are the names of the people that have a roster in a sheet, in the order in which they appear.
+type SheetNames struct {
+	Sheet string
+	Names []string
+}
+
+// FindNames lists the people in every sheet of a workbook, detecting the layout of each sheet.
+func FindNames(book Workbook) ([]SheetNames, error) {
+	return FindNamesInLayout(book, Layout{})
+}
+
+// FindNamesInLayout lists the people in the sheets of a workbook that is laid out as described. A person is a row below
+// a date row with a name, also when there are no shifts in it. Sheets without people are left out.
+func FindNamesInLayout(book Workbook, layout Layout) ([]SheetNames, error) {
+	if problems := layout.Validate(); len(problems) > 0 {
+		return nil, fmt.Errorf("invalid layout %s: %s", layout.Name, strings.Join(problems, "; "))
+	}
+
+	pattern := regexp.MustCompile(layout.SheetPattern)
+	found := []SheetNames{}
+
+	for _, sheet := range book.Sheets() {
+		if !pattern.MatchString(sheet) {
+			continue
+		}
+
+		var names []string
+		var err error
+
+		switch layout.Orientation {
+		case OrientationVertical:
+			names, err = sheetNames(transpose(book), sheet, layout)
+		case OrientationHorizontal:
+			names, err = sheetNames(book, sheet, layout)
+		default:
+			names, err = sheetNames(book, sheet, layout)
+
+			if err == nil && len(names) == 0 {
+				names, err = sheetNames(transpose(book), sheet, layout)
+			}
+		}
+
+		if err != nil {
+			return nil, fmt.Errorf("error in processing sheet %s: %w", sheet, err)
+		}
+
+		if len(names) > 0 {
+			found = append(found, SheetNames{Sheet: sheet, Names: names})
+		}
+	}
+
+	return found, nil
+}
+
+func sheetNames(book Workbook, sheet string, layout Layout) ([]string, error) {
+	names := []string{}
+
+	err := scanPeople(book, sheet, layout, func(name string, row sheetRow) {
+		names = append(names, name)
+	})
+
+	if err != nil {
+		return nil, err
+	}
+
+	return names, nil
+}
+
+// scanPeople calls found for every row with the roster of a person: a row below a date row, with a name. A sheet
+// without the date row of the layout has no people.
+func scanPeople(book Workbook, sheet string, layout Layout, found func(name string, row sheetRow)) error {
+	err := scanSheet(book, sheet, layout, func(row sheetRow) bool {
+		if name := strings.TrimSpace(row.name); row.dates != nil && !row.isDateRow && name != "" {
+			found(name, row)
+		}
+
+		return true
+	})
+
+	if errors.Is(err, NotAScheduleSheet) {
+		return nil
+	}
+
+	return err
+}
@@ -0,0 +1,83 @@
+package excelreader_test
+
+import (
+	"reflect"
+	"rooster-importer/pkg/excelreader"
+	"strings"
+	"testing"
+
+	"github.com/xuri/excelize/v2"
+)
+
+func TestFindNames(t *testing.T) {
+	file := excelize.NewFile()
+	file.SetSheetName("Sheet1", "Januari")
+	file.NewSheet("Uitleg")
+	file.SetCellValue("Uitleg", "A1", "Jan de Vries heeft vakantie")
+
+	rows := [][]interface{}{
+		{"Rooster januari"},
+		{"Naam"},
+		{"Jan de Vries"},
+		{"Piet Jansen"},
+		{},
+		{"Kees Bakker"}, // without shifts, but still in the roster
+		{"  Marie Dubois "},
+	}
+
+	for i := range strings.Fields(rosterShifts) {
+		rows[1] = append(rows[1], rosterStart.AddDate(0, 0, i).Format("2006-1-2"))
+		rows[2] = append(rows[2], "d")
+		rows[3] = append(rows[3], "x")
+		rows[6] = append(rows[6], "n")
+	}
+
+	for i, row := range rows {
+		cell, _ := excelize.CoordinatesToCellName(1, i+1)
+		file.SetSheetRow("Januari", cell, &row)
+	}
+
+	buffer, err := file.WriteToBuffer()
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	book, err := excelreader.ReadWorkbook(buffer, "rooster.xlsx")
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	names, err := excelreader.FindNames(book)
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expected := []excelreader.SheetNames{{Sheet: "Januari", Names: []string{"Jan de Vries", "Piet Jansen", "Kees Bakker", "Marie Dubois"}}}
+
+	if !reflect.DeepEqual(names, expected) {
+		t.Errorf("expected %v, got %v", expected, names)
+	}
+}
+
+func TestFindNamesVertical(t *testing.T) {
+	book, err := excelreader.ReadWorkbook(strings.NewReader(verticalRoster()), "rooster.csv")
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	names, err := excelreader.FindNames(book)
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expected := []excelreader.SheetNames{{Sheet: "rooster", Names: []string{"Someone", "Firstname Lastname"}}}
+
+	if !reflect.DeepEqual(names, expected) {
+		t.Errorf("expected %v, got %v", expected, names)
+	}
+}
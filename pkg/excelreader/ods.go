@@ -0,0 +1,212 @@
+package excelreader
+
+import (
+	"archive/zip"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	odsTableNs  = "urn:oasis:names:tc:opendocument:xmlns:table:1.0"
+	odsOfficeNs = "urn:oasis:names:tc:opendocument:xmlns:office:1.0"
+	odsTextNs   = "urn:oasis:names:tc:opendocument:xmlns:text:1.0"
+)
+
+// readOds reads OpenDocument spreadsheets, as saved by LibreOffice. Cells with a date value are recognized as dates,
+// whatever their display format is.
+func readOds(archive *zip.Reader) (Workbook, error) {
+	for _, f := range archive.File {
+		if f.Name != "content.xml" {
+			continue
+		}
+
+		r, err := f.Open()
+
+		if err != nil {
+			return nil, err
+		}
+		defer r.Close()
+
+		sheets, err := parseOdsContent(r)
+
+		if err != nil {
+			return nil, fmt.Errorf("cannot read spreadsheet: %w", err)
+		}
+
+		return &memoryWorkbook{sheets: sheets}, nil
+	}
+
+	return nil, fmt.Errorf("cannot read spreadsheet: content.xml is missing")
+}
+
+func repeatAttr(el xml.StartElement, name string) int {
+	for _, attr := range el.Attr {
+		if attr.Name.Space == odsTableNs && attr.Name.Local == name {
+			if n, err := strconv.Atoi(attr.Value); err == nil && n > 0 {
+				return n
+			}
+		}
+	}
+
+	return 1
+}
+
+func attr(el xml.StartElement, space, name string) string {
+	for _, attr := range el.Attr {
+		if attr.Name.Space == space && attr.Name.Local == name {
+			return attr.Value
+		}
+	}
+
+	return ""
+}
+
+func parseOdsDate(value string) (time.Time, bool) {
+	for _, format := range []string{"2006-01-02", "2006-01-02T15:04:05"} {
+		if t, err := time.Parse(format, value); err == nil {
+			return t, true
+		}
+	}
+
+	return time.Time{}, false
+}
+
+// odsRow collects the cells of a row. Empty cells and rows are often repeated up to the maximum size of a sheet, so
+// they are only added once something follows them.
+type odsRow struct {
+	cells        []string
+	dates        map[int]time.Time
+	spans        map[int]cellPosition
+	pendingEmpty int
+}
+
+// add adds a cell, which spans a number of columns and rows when it is merged with the (covered) cells next to it
+func (r *odsRow) add(text string, date time.Time, isDate bool, repeat int, span cellPosition) {
+	if text == "" && !isDate {
+		r.pendingEmpty += repeat
+		return
+	}
+
+	for ; r.pendingEmpty > 0; r.pendingEmpty-- {
+		r.cells = append(r.cells, "")
+	}
+
+	for i := 0; i < repeat; i++ {
+		if isDate {
+			r.dates[len(r.cells)] = date
+		}
+
+		if span.col > 1 || span.row > 1 {
+			r.spans[len(r.cells)] = span
+		}
+
+		r.cells = append(r.cells, text)
+	}
+}
+
+func parseOdsContent(r io.Reader) ([]*memorySheet, error) {
+	decoder := xml.NewDecoder(r)
+
+	sheets := []*memorySheet{}
+	var sheet *memorySheet
+	var row *odsRow
+	rowRepeat, pendingRows := 1, 0
+
+	inCell := false
+	cellText := strings.Builder{}
+	cellRepeat, paragraphs := 1, 0
+	var cellDate time.Time
+	cellIsDate := false
+	var cellSpan cellPosition
+
+	for {
+		token, err := decoder.Token()
+
+		if err == io.EOF {
+			return sheets, nil
+		} else if err != nil {
+			return nil, err
+		}
+
+		switch t := token.(type) {
+		case xml.StartElement:
+			switch {
+			case t.Name.Space == odsTableNs && t.Name.Local == "table":
+				sheet = &memorySheet{name: attr(t, odsTableNs, "name"), dates: make(map[cellPosition]time.Time)}
+				pendingRows = 0
+			case t.Name.Space == odsTableNs && t.Name.Local == "table-row" && sheet != nil:
+				row = &odsRow{dates: make(map[int]time.Time), spans: make(map[int]cellPosition)}
+				rowRepeat = repeatAttr(t, "number-rows-repeated")
+			case t.Name.Space == odsTableNs && (t.Name.Local == "table-cell" || t.Name.Local == "covered-table-cell") && row != nil:
+				inCell = true
+				cellText.Reset()
+				cellRepeat = repeatAttr(t, "number-columns-repeated")
+				paragraphs = 0
+				cellIsDate = false
+				cellSpan = cellPosition{repeatAttr(t, "number-columns-spanned"), repeatAttr(t, "number-rows-spanned")}
+
+				if attr(t, odsOfficeNs, "value-type") == "date" {
+					cellDate, cellIsDate = parseOdsDate(attr(t, odsOfficeNs, "date-value"))
+				}
+			case t.Name.Space == odsTextNs && t.Name.Local == "p" && inCell:
+				if paragraphs > 0 {
+					cellText.WriteString("\n")
+				}
+
+				paragraphs += 1
+			case t.Name.Space == odsTextNs && t.Name.Local == "s" && inCell:
+				spaces, err := strconv.Atoi(attr(t, odsTextNs, "c"))
+
+				if err != nil || spaces < 1 {
+					spaces = 1
+				}
+
+				cellText.WriteString(strings.Repeat(" ", spaces))
+			}
+
+		case xml.CharData:
+			if inCell && paragraphs > 0 {
+				cellText.Write(t)
+			}
+
+		case xml.EndElement:
+			switch {
+			case t.Name.Space == odsTableNs && (t.Name.Local == "table-cell" || t.Name.Local == "covered-table-cell") && inCell:
+				row.add(cellText.String(), cellDate, cellIsDate, cellRepeat, cellSpan)
+				inCell = false
+			case t.Name.Space == odsTableNs && t.Name.Local == "table-row" && row != nil:
+				if len(row.cells) == 0 {
+					pendingRows += rowRepeat
+				} else {
+					for ; pendingRows > 0; pendingRows-- {
+						sheet.rows = append(sheet.rows, []string{})
+					}
+
+					for i := 0; i < rowRepeat; i++ {
+						for col, date := range row.dates {
+							sheet.dates[cellPosition{col, len(sheet.rows)}] = date
+						}
+
+						for col, span := range row.spans {
+							sheet.merged = append(sheet.merged, CellRange{
+								FromCol: col, FromRow: len(sheet.rows),
+								ToCol: col + span.col - 1, ToRow: len(sheet.rows) + span.row - 1,
+							})
+						}
+
+						sheet.rows = append(sheet.rows, row.cells)
+					}
+				}
+
+				row = nil
+			case t.Name.Space == odsTableNs && t.Name.Local == "table" && sheet != nil:
+				sheets = append(sheets, sheet)
+				sheet = nil
+			}
+		}
+	}
+}
@@ -4,11 +4,10 @@ import (
 	"errors"
 	"fmt"
 	"io"
+	"regexp"
 	"sort"
 	"strings"
 	"time"
-
-	"github.com/xuri/excelize/v2"
 )
 
 type ScheduleEntry struct {
@@ -16,11 +15,23 @@ type ScheduleEntry struct {
 	Shift string
 }
 
+// NoEntriesFoundError is returned when sheets don't have the roster of the user, or when the name of the user matches
+// nobody in the roster. In that case Name is the name of the user, and Closest are the names that resemble it most.
 type NoEntriesFoundError struct {
 	sheets []string
+
+	Name    string
+	Closest []string
 }
 
 func (e *NoEntriesFoundError) Error() string {
+	if e.Name != "" || len(e.sheets) == 0 {
+		if len(e.Closest) == 0 {
+			return fmt.Sprintf("didn't find %q in the roster", e.Name)
+		}
+
+		return fmt.Sprintf("didn't find %q in the roster, did you mean %s?", e.Name, strings.Join(e.Closest, ", "))
+	}
 
 	if len(e.sheets) == 1 {
 		return fmt.Sprintf("didn't find entries in sheet %s", e.sheets[0])
@@ -45,25 +56,52 @@ var DateParseError error = errors.New("couldn't parse date")
 var NoEntriesInSheet error = errors.New("no entries found")
 var NotAScheduleSheet error = errors.New("sheet does not contain dates")
 
+// maxDateGap is the largest step between two dates in a date row
+const maxDateGap = 7 * 24 * time.Hour
+
+// FindScheduleEntries reads the roster of name from an .xlsx, .xls or .ods file, which ReadWorkbook recognizes from
+// its contents.
 func FindScheduleEntries(reader io.ReadCloser, name string) ([]ScheduleEntry, error) {
-	file, err := excelize.OpenReader(reader)
+	defer reader.Close()
+
+	book, err := ReadWorkbook(reader, "")
 
 	if err != nil {
 		return nil, err
 	}
 
-	sheets := file.GetSheetList()
+	return FindEntries(book, name)
+}
 
-	if len(sheets) == 0 {
+// FindEntries reads the roster of name from every sheet of a workbook, detecting the layout of each sheet.
+func FindEntries(book Workbook, name string) ([]ScheduleEntry, error) {
+	return FindEntriesInLayout(book, name, Layout{})
+}
+
+// findEntries collects the entries that process finds in the sheets of a workbook of which the name matches pattern.
+func findEntries(book Workbook, pattern *regexp.Regexp, process func(sheet string) ([]ScheduleEntry, error)) ([]ScheduleEntry, error) {
+	sheets := []string{}
+
+	for _, sheet := range book.Sheets() {
+		if pattern.MatchString(sheet) {
+			sheets = append(sheets, sheet)
+		}
+	}
+
+	if len(book.Sheets()) == 0 {
 		return nil, errors.New("No sheets found in the Excel file")
 	}
 
+	if len(sheets) == 0 {
+		return nil, fmt.Errorf("no sheets match %s", pattern)
+	}
+
 	allEntries := []ScheduleEntry{}
 
 	noEntriesError := &NoEntriesFoundError{}
 
 	for _, sheet := range sheets {
-		entries, err := processSheet(file, sheet, name)
+		entries, err := process(sheet)
 
 		if err != nil {
 			if errors.Is(err, NoEntriesInSheet) || errors.Is(err, NotAScheduleSheet) {
@@ -85,65 +123,198 @@ func FindScheduleEntries(reader io.ReadCloser, name string) ([]ScheduleEntry, er
 	return allEntries, noEntriesError
 }
 
-func processSheet(file *excelize.File, sheet string, name string) ([]ScheduleEntry, error) {
-	rows, err := file.GetRows(sheet)
+// sheetRow is a row of a sheet as seen by scanSheet. Dates holds the dates of the columns, and is nil until a date row
+// has been found.
+type sheetRow struct {
+	index     int
+	name      string
+	cells     []string
+	dates     map[int]time.Time
+	isDateRow bool
+}
+
+// scanSheet calls visit for every row of a sheet that is laid out as described, until visit returns false.
+func scanSheet(book Workbook, sheet string, layout Layout, visit func(row sheetRow) bool) error {
+	rows, err := book.Rows(sheet)
+
+	if err != nil {
+		return fmt.Errorf("error in iterating over rows: %w", err)
+	}
+
+	merged, err := book.MergedCells(sheet)
 
 	if err != nil {
-		return nil, fmt.Errorf("error in iterating over rows: %w", err)
+		return fmt.Errorf("error in reading merged cells: %w", err)
+	}
+
+	nameColumn, firstDataColumn := 0, 0
+
+	if layout.NameColumn > 0 {
+		nameColumn = layout.NameColumn - 1
+	}
+
+	if layout.FirstDataColumn > 0 {
+		firstDataColumn = layout.FirstDataColumn - 1
 	}
 
 	var datemapping map[int]time.Time
+	var header []string
+	headeridx := -1
 
 	for rowidx, row := range rows {
-		if len(row) == 0 {
+		if len(row) == 0 || rowidx < layout.HeaderOffset {
 			continue
 		}
 
-		// While iterating over rows, check if the current row contains dates
-		if mapping, ok := findDateRow(row, rowidx, file, sheet); ok {
-			datemapping = mapping
-		}
+		cells := dataCells(row, firstDataColumn)
+		isDateRow := false
 
-		if strings.HasPrefix(row[0], name) {
-			if datemapping == nil {
-				return nil, fmt.Errorf("found %s before knowing the dates: %w", name, NotAScheduleSheet)
-			}
+		// While iterating over rows, check if the current row contains dates, or day numbers below a row of weeks or
+		// months. When the layout tells which row has the dates, only that row is used.
+		if layout.DateRow > 0 {
+			if rowidx == layout.DateRow-1 {
+				mapping, ok := findGivenDateRow(cells, rowidx, book, sheet)
 
-			// Construct a list of schedule entries
-			entries := []ScheduleEntry{}
+				if !ok {
+					mapping, ok = findDayNumberRow(cells, header, headeridx, merged, sheet)
+				}
 
-			for col, date := range datemapping {
-				if col < len(row) {
-					entries = append(entries, ScheduleEntry{
-						Date:  date,
-						Shift: row[col],
-					})
+				if !ok {
+					return fmt.Errorf("row %d does not contain dates: %w", layout.DateRow, NotAScheduleSheet)
 				}
+
+				datemapping, isDateRow = mapping, true
 			}
+		} else if mapping, ok := findDateRow(cells, rowidx, book, sheet); ok {
+			datemapping, isDateRow = mapping, true
+		} else if mapping, ok := findDayNumberRow(cells, header, headeridx, merged, sheet); ok {
+			datemapping, isDateRow = mapping, true
+		}
 
-			sort.Slice(entries, func(i, j int) bool {
-				return entries[i].Date.Compare(entries[j].Date) == -1
-			})
+		header, headeridx = cells, rowidx
+
+		name := ""
+
+		if nameColumn < len(row) {
+			name = row[nameColumn]
+		}
+
+		if !visit(sheetRow{index: rowidx, name: name, cells: cells, dates: datemapping, isDateRow: isDateRow}) {
+			return nil
+		}
+	}
+
+	return nil
+}
+
+// processSheet reads the roster in the first row of a sheet of which the name matches.
+func processSheet(book Workbook, sheet string, matches func(rowName string) bool, layout Layout) ([]ScheduleEntry, error) {
+	var entries []ScheduleEntry
+	var found error
+
+	err := scanSheet(book, sheet, layout, func(row sheetRow) bool {
+		if !matches(row.name) {
+			return true
+		}
 
-			return entries, nil
+		if row.dates == nil {
+			found = fmt.Errorf("found %s before knowing the dates: %w", row.name, NotAScheduleSheet)
+			return false
 		}
+
+		entries = rowEntries(row)
+
+		return false
+	})
+
+	if err != nil {
+		return nil, err
+	}
+
+	if found != nil {
+		return nil, found
 	}
 
-	// if we get here, the loop above didn't yield any entries..
-	// return a "no entries found" error
-	return nil, NoEntriesInSheet
+	if entries == nil {
+		// the scan didn't yield any entries, return a "no entries found" error
+		return nil, NoEntriesInSheet
+	}
+
+	return entries, nil
 }
 
-func findDateRow(row []string, rowidx int, file *excelize.File, sheetName string) (map[int]time.Time, bool) {
+// rowEntries returns the shifts in a row on the dates of the columns, sorted by date.
+func rowEntries(row sheetRow) []ScheduleEntry {
+	entries := []ScheduleEntry{}
+
+	for col, date := range row.dates {
+		if col < len(row.cells) {
+			entries = append(entries, ScheduleEntry{
+				Date:  date,
+				Shift: row.cells[col],
+			})
+		}
+	}
+
+	sort.Slice(entries, func(i, j int) bool {
+		return entries[i].Date.Compare(entries[j].Date) == -1
+	})
+
+	return entries
+}
+
+// dataCells returns the row without the cells before the first column that contains dates and shifts, which are
+// left empty so that the columns keep their index.
+func dataCells(row []string, firstDataColumn int) []string {
+	if firstDataColumn == 0 {
+		return row
+	}
+
+	cells := make([]string, len(row))
+
+	if firstDataColumn < len(row) {
+		copy(cells[firstDataColumn:], row[firstDataColumn:])
+	}
+
+	return cells
+}
+
+func findDateRow(row []string, rowidx int, book Workbook, sheetName string) (map[int]time.Time, bool) {
+	datemap, datelocations := parseDateCells(row, rowidx, book, sheetName)
+
+	if !validDateRow(datemap, datelocations) {
+		return nil, false
+	}
+
+	return datemap, true
+}
+
+// findGivenDateRow reads the dates in a row that is known to be the date row, which doesn't need to pass the checks
+// that findDateRow uses to recognize a date row.
+func findGivenDateRow(row []string, rowidx int, book Workbook, sheetName string) (map[int]time.Time, bool) {
+	datemap, datelocations := parseDateCells(row, rowidx, book, sheetName)
+
+	if len(datelocations) == 0 {
+		return nil, false
+	}
+
+	inferYears(datemap, datelocations)
+
+	return datemap, true
+}
+
+// parseDateCells returns the dates in a row by column, and the columns that have a date from left to right.
+func parseDateCells(row []string, rowidx int, book Workbook, sheetName string) (map[int]time.Time, []int) {
 	datemap := make(map[int]time.Time)
 	datelocations := []int{}
 
 	for x, cell := range row {
 
-		// first try interpreting the date format from the stylesheet of the excel file
-		parsed, err := parseUsingStylesheet(x, rowidx, file, sheetName, cell)
+		// first try the date format that the file knows of
+		parsed, ok := book.Date(sheetName, x, rowidx, cell)
+		var err error
 
-		if err != nil {
+		if !ok {
 			// try yyyy-mm-dd
 			parsed, err = time.Parse("2006-1-2", cell)
 		}
@@ -153,71 +324,95 @@ func findDateRow(row []string, rowidx int, file *excelize.File, sheetName string
 			parsed, err = time.Parse("2-1-2006", cell)
 		}
 
+		if err != nil {
+			// and finally dates with the month written out, such as "ma 3 jan"
+			if header, ok := parseDateHeader(cell); ok {
+				parsed, err = header, nil
+			}
+		}
+
 		if err == nil {
 			datemap[x] = parsed
 			datelocations = append(datelocations, x)
 		}
 	}
 
+	return datemap, datelocations
+}
+
+// validDateRow tells whether the dates found in a row, at the given columns from left to right, make up a date row.
+// Dates without a year get their year here.
+func validDateRow(datemap map[int]time.Time, datelocations []int) bool {
 	if len(datelocations) < 10 {
-		return nil, false
+		return false
 	}
 
+	inferYears(datemap, datelocations)
+
+	// Dates must go up from left to right, which also rejects rows where days and months were mixed up (like American
+	// dates read as European ones). Days may be left out, such as weekends or holidays, but not more than a week.
 	for i, loc := range datelocations[:len(datelocations)-1] {
-		if datemap[loc].Add(24*time.Hour) != datemap[datelocations[i+1]] {
-			return nil, false
+		current, next := datemap[loc], datemap[datelocations[i+1]]
+
+		if !next.After(current) || next.Sub(current) > maxDateGap {
+			return false
 		}
 	}
 
-	return datemap, true
+	return true
 }
 
-func parseUsingStylesheet(col, row int, file *excelize.File, sheetName, content string) (time.Time, error) {
-	cellname, err := excelize.CoordinatesToCellName(col+1, row+1, false)
+// inferYears gives dates that were written without a year (year 0) the year of the dates around them. The first date
+// with a year decides, or the current year if there is none. Going to the right, the year increases when the month
+// wraps around, as it does in a roster that runs from December into January.
+func inferYears(datemap map[int]time.Time, locations []int) {
+	first := -1
 
-	if err != nil {
-		return time.Time{}, err
+	for i, loc := range locations {
+		if datemap[loc].Year() != 0 {
+			first = i
+			break
+		}
 	}
 
-	style, err := file.GetCellStyle(sheetName, cellname)
+	withYear := func(date time.Time, year int) time.Time {
+		return time.Date(year, date.Month(), date.Day(), date.Hour(), date.Minute(), 0, 0, date.Location())
+	}
 
-	if err != nil {
-		return time.Time{}, err
+	if first == -1 {
+		first = 0
+		datemap[locations[0]] = withYear(datemap[locations[0]], time.Now().Year())
 	}
 
-	if stylesheet := file.Styles.CellXfs.Xf; style < len(stylesheet) {
-		var format string
+	for i := first + 1; i < len(locations); i++ {
+		date, previous := datemap[locations[i]], datemap[locations[i-1]]
 
-		if stylesheet[style].NumFmtID == nil {
-			return time.Time{}, DateParseError
+		if date.Year() != 0 {
+			continue
 		}
 
-		switch *stylesheet[style].NumFmtID {
-		case 14:
-			//"mm-dd-yy"
-			format = "01-02-06"
-		case 15:
-			//"d-mmm-yy"
-			format = "02-Jan-06"
-		case 16:
-			//"d-mmm"
-			format = "02-Jan"
-		case 17:
-			//"mmm-yy"
-			format = "Jan-06"
-		case 22:
-			//"m/d/yy h:mm"
-			format = "1/2/06 15:04"
-		default:
-			return time.Time{}, DateParseError
+		year := previous.Year()
+
+		if date.Month() < previous.Month() {
+			year += 1
 		}
 
-		t, err := time.Parse(format, content)
+		datemap[locations[i]] = withYear(date, year)
+	}
 
-		if err == nil {
-			return t, nil
+	for i := first - 1; i >= 0; i-- {
+		date, next := datemap[locations[i]], datemap[locations[i+1]]
+
+		if date.Year() != 0 {
+			continue
 		}
-	}
 
-	return time.Time{}, DateParseError
+		year := next.Year()
+
+		if date.Month() > next.Month() {
+			year -= 1
+		}
+
+		datemap[locations[i]] = withYear(date, year)
+	}
 }
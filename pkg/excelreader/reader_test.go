@@ -4,6 +4,7 @@ import (
 	"fmt"
 	"os"
 	"rooster-importer/pkg/excelreader"
+	"strings"
 	"testing"
 	"time"
 )
@@ -58,3 +59,55 @@ func TestDateParsing(t *testing.T) {
 		t.Errorf("wrong day (expected 17, is %d): year: %d, month: %s", day, year, month)
 	}
 }
+
+func TestDateRowWithGaps(t *testing.T) {
+	// weekdays only, with a week total column between the weeks
+	contents := "Naam;ma 1-1-2024;2-1-2024;3-1-2024;4-1-2024;5-1-2024;Totaal;8-1-2024;9-1-2024;10-1-2024;11-1-2024;12-1-2024;Totaal\n" +
+		"Firstname Lastname;d;d;a;a;n;32;x;d;d;t;t;40\n"
+
+	book, err := excelreader.ReadWorkbook(strings.NewReader(contents), "rooster.csv")
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	entries, err := excelreader.FindEntries(book, "Firstname")
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expected := map[string]string{"2024-01-01": "d", "2024-01-05": "n", "2024-01-08": "x", "2024-01-12": "t"}
+
+	if len(entries) != 10 {
+		t.Fatalf("expected 10 entries, got %d: %v", len(entries), entries)
+	}
+
+	for _, entry := range entries {
+		if shift, ok := expected[entry.Date.Format(time.DateOnly)]; ok && shift != entry.Shift {
+			t.Errorf("expected %s on %s, got %s", shift, entry.Date.Format(time.DateOnly), entry.Shift)
+		}
+	}
+}
+
+func TestDateRowMustIncrease(t *testing.T) {
+	rows := map[string]string{
+		// days and months swapped halfway, as happens when American and European dates are mixed
+		"mixed up":   "Naam;1-1-2024;2-1-2024;3-1-2024;4-1-2024;5-1-2024;1-6-2024;1-7-2024;1-8-2024;1-9-2024;1-10-2024",
+		"decreasing": "Naam;10-1-2024;9-1-2024;8-1-2024;7-1-2024;6-1-2024;5-1-2024;4-1-2024;3-1-2024;2-1-2024;1-1-2024",
+		"large gap":  "Naam;1-1-2024;2-1-2024;3-1-2024;4-1-2024;5-1-2024;1-2-2024;2-2-2024;3-2-2024;4-2-2024;5-2-2024",
+	}
+
+	for name, row := range rows {
+		contents := row + "\nFirstname Lastname;d;d;d;d;d;d;d;d;d;d\n"
+		book, err := excelreader.ReadWorkbook(strings.NewReader(contents), "rooster.csv")
+
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if _, err := excelreader.FindEntries(book, "Firstname"); err == nil {
+			t.Errorf("%s: expected the row not to be taken for dates", name)
+		}
+	}
+}
@@ -0,0 +1,109 @@
+package excelreader
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+// FindTeamEntries reads the rosters of everyone in a workbook, detecting the layout of each sheet.
+func FindTeamEntries(book Workbook) (map[string][]ScheduleEntry, error) {
+	return FindTeamEntriesInLayout(book, Layout{})
+}
+
+// FindTeamEntriesInLayout reads the rosters of everyone in the sheets of a workbook that is laid out as described, in
+// a single pass over every sheet. The rosters are keyed by name. The rows of a person in several sheets are combined,
+// even when the name is written differently, under the name as it is written first.
+//
+// As with FindEntriesInLayout, the rosters are returned together with a *NoEntriesFoundError when some sheets have no
+// people in them.
+func FindTeamEntriesInLayout(book Workbook, layout Layout) (map[string][]ScheduleEntry, error) {
+	if problems := layout.Validate(); len(problems) > 0 {
+		return nil, fmt.Errorf("invalid layout %s: %s", layout.Name, strings.Join(problems, "; "))
+	}
+
+	team := make(map[string][]ScheduleEntry)
+	names := make(map[string]string)
+
+	pattern := regexp.MustCompile(layout.SheetPattern)
+
+	_, err := findEntries(book, pattern, func(sheet string) ([]ScheduleEntry, error) {
+		var people []person
+		var err error
+
+		switch layout.Orientation {
+		case OrientationHorizontal:
+			people, err = sheetPeople(book, sheet, layout)
+		case OrientationVertical:
+			people, err = sheetPeople(transpose(book), sheet, layout)
+		default:
+			people, err = sheetPeople(book, sheet, layout)
+
+			if err == nil && len(people) == 0 {
+				people, err = sheetPeople(transpose(book), sheet, layout)
+			}
+		}
+
+		if err != nil {
+			return nil, err
+		}
+
+		if len(people) == 0 {
+			return nil, NoEntriesInSheet
+		}
+
+		for _, p := range people {
+			key := strings.Join(nameTokens(p.name), " ")
+
+			if _, ok := names[key]; !ok {
+				names[key] = p.name
+			}
+
+			team[names[key]] = append(team[names[key]], p.entries...)
+		}
+
+		return nil, nil
+	})
+
+	for _, entries := range team {
+		sort.SliceStable(entries, func(i, j int) bool {
+			return entries[i].Date.Before(entries[j].Date)
+		})
+	}
+
+	var noEntriesError *NoEntriesFoundError
+
+	if err != nil && !errors.As(err, &noEntriesError) {
+		return nil, err
+	}
+
+	return team, err
+}
+
+// person is a row with the roster of someone.
+type person struct {
+	name    string
+	entries []ScheduleEntry
+}
+
+// sheetPeople returns the people in a sheet that have at least one shift on one of the dates.
+func sheetPeople(book Workbook, sheet string, layout Layout) ([]person, error) {
+	people := []person{}
+
+	err := scanPeople(book, sheet, layout, func(name string, row sheetRow) {
+		for col := range row.dates {
+			if col < len(row.cells) && strings.TrimSpace(row.cells[col]) != "" {
+				people = append(people, person{name: name, entries: rowEntries(row)})
+				return
+			}
+		}
+	})
+
+	if err != nil {
+		return nil, err
+	}
+
+	return people, nil
+}
@@ -0,0 +1,79 @@
+package excelreader_test
+
+import (
+	"errors"
+	"rooster-importer/pkg/excelreader"
+	"testing"
+
+	"github.com/xuri/excelize/v2"
+)
+
+func TestFindTeamEntries(t *testing.T) {
+	file := excelize.NewFile()
+	file.SetSheetName("Sheet1", "Januari")
+	file.NewSheet("Februari")
+	file.NewSheet("Uitleg")
+	file.SetCellValue("Uitleg", "A1", "d is een dagdienst")
+
+	months := map[string][][]interface{}{
+		"Januari":  {{"Naam"}, {"Jan de Vries"}, {"Piet Jansen"}, {"Leeg"}},
+		"Februari": {{"Naam"}, {"jan de vries"}},
+	}
+
+	for sheet, rows := range months {
+		month := 1
+
+		if sheet == "Februari" {
+			month = 2
+		}
+
+		for day := 1; day <= 14; day++ {
+			rows[0] = append(rows[0], rosterStart.AddDate(0, month-1, day-1).Format("2006-1-2"))
+			rows[1] = append(rows[1], "d")
+
+			if len(rows) > 2 {
+				rows[2] = append(rows[2], "n")
+				rows[3] = append(rows[3], "")
+			}
+		}
+
+		for i, row := range rows {
+			cell, _ := excelize.CoordinatesToCellName(1, i+1)
+			file.SetSheetRow(sheet, cell, &row)
+		}
+	}
+
+	buffer, err := file.WriteToBuffer()
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	book, err := excelreader.ReadWorkbook(buffer, "rooster.xlsx")
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	team, err := excelreader.FindTeamEntries(book)
+
+	var noEntriesError *excelreader.NoEntriesFoundError
+
+	if !errors.As(err, &noEntriesError) {
+		t.Errorf("expected the sheet without people to be reported, got %v", err)
+	}
+
+	if len(team) != 2 {
+		t.Fatalf("expected the rosters of 2 people, got %v", team)
+	}
+
+	jan := team["Jan de Vries"]
+
+	if len(jan) != 28 || jan[0].Date != rosterStart || jan[27].Date != rosterStart.AddDate(0, 1, 13) {
+		t.Errorf("expected the rosters of Jan in both sheets to be combined, got %v", jan)
+	}
+
+	if piet := team["Piet Jansen"]; len(piet) != 14 || piet[0].Shift != "n" {
+		t.Errorf("expected the night shifts of Piet, got %v", piet)
+	}
+}
@@ -0,0 +1,137 @@
+package excelreader
+
+import (
+	"archive/zip"
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// Workbook is a roster file, read as sheets of rows of cell text. Depending on the file format, cells also know how
+// they are formatted, which is used to recognize dates.
+type Workbook interface {
+	Sheets() []string
+	Rows(sheet string) ([][]string, error)
+	// Date returns the date in a cell, when the file tells that the cell contains a date. Content is the text of the
+	// cell as returned by Rows. Columns and rows start at 0. Dates that were written without a year are in year 0.
+	Date(sheet string, col, row int, content string) (time.Time, bool)
+	// MergedCells returns the ranges of cells that are merged into one. The text of a merged cell is in the first cell
+	// of the range, the other cells are empty.
+	MergedCells(sheet string) ([]CellRange, error)
+}
+
+// CellRange is a block of cells, from the top left to the bottom right cell. Columns and rows start at 0.
+type CellRange struct {
+	FromCol, FromRow int
+	ToCol, ToRow     int
+}
+
+// ErrLegacyXls is returned for .xls files of Excel 95 and older, of which only the later Excel 97-2003 format is read.
+var ErrLegacyXls = errors.New("old .xls files of Excel 95 and before are not supported, open the roster in Excel or LibreOffice and save it as .xlsx, .ods or .csv")
+
+// ErrUnsupportedFile is returned for files that are not a spreadsheet, or not in one of the formats that are read.
+var ErrUnsupportedFile = errors.New("unsupported file type")
+
+var (
+	zipMagic  = []byte("PK\x03\x04")
+	ole2Magic = []byte{0xD0, 0xCF, 0x11, 0xE0, 0xA1, 0xB1, 0x1A, 0xE1}
+)
+
+const odsMimetype = "application/vnd.oasis.opendocument.spreadsheet"
+
+// ReadWorkbook reads an .xlsx, .xls, .ods, .csv or .tsv file. The format is recognized from the contents of the file where
+// possible, and otherwise from the extension of the filename. Only files named .csv, .txt, .tsv or .tab are read as
+// separated values, other files that are not recognized give an ErrUnsupportedFile.
+func ReadWorkbook(reader io.Reader, filename string) (Workbook, error) {
+	contents, err := io.ReadAll(reader)
+
+	if err != nil {
+		return nil, err
+	}
+
+	switch {
+	case bytes.HasPrefix(contents, ole2Magic):
+		return readXls(contents)
+	case bytes.HasPrefix(contents, zipMagic):
+		archive, err := zip.NewReader(bytes.NewReader(contents), int64(len(contents)))
+
+		if err != nil {
+			return nil, err
+		}
+
+		if isOds(archive) {
+			return readOds(archive)
+		}
+
+		return readXlsx(contents)
+	}
+
+	switch extension := strings.ToLower(filepath.Ext(filename)); extension {
+	case ".xls":
+		return nil, errors.New("cannot read .xls file: it is not an Excel 97-2003 workbook")
+	case ".csv", ".txt":
+		return readCsv(contents, filename, 0)
+	case ".tsv", ".tab":
+		return readCsv(contents, filename, '\t')
+	case "":
+		return nil, fmt.Errorf("%w, choose an .xlsx, .xls, .ods, .csv or .tsv file", ErrUnsupportedFile)
+	default:
+		return nil, fmt.Errorf("%w %s, choose an .xlsx, .xls, .ods, .csv or .tsv file", ErrUnsupportedFile, extension)
+	}
+}
+
+// isOds tells whether a zip file is an OpenDocument spreadsheet, which stores its type in the mimetype file
+func isOds(archive *zip.Reader) bool {
+	for _, f := range archive.File {
+		if f.Name != "mimetype" {
+			continue
+		}
+
+		r, err := f.Open()
+
+		if err != nil {
+			return false
+		}
+		defer r.Close()
+
+		mimetype, err := io.ReadAll(r)
+
+		return err == nil && strings.TrimSpace(string(mimetype)) == odsMimetype
+	}
+
+	return false
+}
+
+// SelectSheets returns the workbook with only the given sheets, in the order of the workbook. All sheets are kept when
+// sheets is nil.
+func SelectSheets(book Workbook, sheets []string) Workbook {
+	if sheets == nil {
+		return book
+	}
+
+	return &selectedSheets{Workbook: book, sheets: sheets}
+}
+
+type selectedSheets struct {
+	Workbook
+	sheets []string
+}
+
+func (s *selectedSheets) Sheets() []string {
+	selected := []string{}
+
+	for _, sheet := range s.Workbook.Sheets() {
+		for _, name := range s.sheets {
+			if sheet == name {
+				selected = append(selected, sheet)
+				break
+			}
+		}
+	}
+
+	return selected
+}
@@ -0,0 +1,384 @@
+package excelreader_test
+
+import (
+	"archive/zip"
+	"bytes"
+	"errors"
+	"fmt"
+	"os"
+	"reflect"
+	"rooster-importer/pkg/excelreader"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/xuri/excelize/v2"
+)
+
+var rosterStart = time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+
+const rosterShifts = "d t a n x x x d d d d d x x"
+
+func checkEntries(t *testing.T, entries []excelreader.ScheduleEntry) {
+	t.Helper()
+
+	shifts := strings.Fields(rosterShifts)
+
+	if len(entries) != len(shifts) {
+		t.Fatalf("expected %d entries, got %d: %v", len(shifts), len(entries), entries)
+	}
+
+	for i, entry := range entries {
+		if expected := rosterStart.AddDate(0, 0, i); !entry.Date.Equal(expected) {
+			t.Errorf("entry %d: expected date %s, got %s", i, expected.Format(time.DateOnly), entry.Date.Format(time.DateOnly))
+		}
+
+		if entry.Shift != shifts[i] {
+			t.Errorf("entry %d: expected shift %s, got %s", i, shifts[i], entry.Shift)
+		}
+	}
+}
+
+func separatedRoster(separator, dateFormat string) string {
+	dates := []string{"Naam"}
+
+	for i := range strings.Fields(rosterShifts) {
+		dates = append(dates, rosterStart.AddDate(0, 0, i).Format(dateFormat))
+	}
+
+	return strings.Join(dates, separator) + "\n" +
+		"Someone" + separator + strings.Repeat("x"+separator, 13) + "x\n" +
+		"Firstname Lastname" + separator + strings.Join(strings.Fields(rosterShifts), separator) + "\n"
+}
+
+func TestReadCsv(t *testing.T) {
+	contents := "\xEF\xBB\xBF" + separatedRoster(";", "2-1-2006")
+
+	book, err := excelreader.ReadWorkbook(strings.NewReader(contents), "rooster.csv")
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if sheets := book.Sheets(); len(sheets) != 1 || sheets[0] != "rooster" {
+		t.Errorf("expected a single sheet named after the file, got %v", sheets)
+	}
+
+	entries, err := excelreader.FindEntries(book, "Firstname")
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	checkEntries(t, entries)
+}
+
+func TestReadUnsupportedFile(t *testing.T) {
+	contents := "%PDF-1.4\n" + separatedRoster(",", "2006-1-2")
+
+	for _, filename := range []string{"rooster.pdf", "rooster.docx", "rooster"} {
+		if _, err := excelreader.ReadWorkbook(strings.NewReader(contents), filename); !errors.Is(err, excelreader.ErrUnsupportedFile) {
+			t.Errorf("%s: expected an unsupported file type, got %v", filename, err)
+		}
+	}
+
+	book, err := excelreader.ReadWorkbook(strings.NewReader(separatedRoster(",", "2006-1-2")), "rooster.txt")
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	entries, err := excelreader.FindEntries(book, "Firstname")
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	checkEntries(t, entries)
+}
+
+func TestReadTsv(t *testing.T) {
+	book, err := excelreader.ReadWorkbook(strings.NewReader(separatedRoster("\t", "2006-01-02")), "rooster.tsv")
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	entries, err := excelreader.FindEntries(book, "Firstname")
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	checkEntries(t, entries)
+}
+
+// xlsxRoster creates an Excel file with a date row in the given style, where setDate fills in the date cells
+func xlsxRoster(t *testing.T, style *excelize.Style, setDate func(file *excelize.File, cell string, date time.Time)) *bytes.Buffer {
+	t.Helper()
+
+	file := excelize.NewFile()
+	styleId, err := file.NewStyle(style)
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	file.SetCellValue("Sheet1", "A1", "Naam")
+	file.SetCellValue("Sheet1", "A2", "Firstname Lastname")
+
+	for i, shift := range strings.Fields(rosterShifts) {
+		date, _ := excelize.CoordinatesToCellName(i+2, 1)
+		entry, _ := excelize.CoordinatesToCellName(i+2, 2)
+
+		setDate(file, date, rosterStart.AddDate(0, 0, i))
+		file.SetCellStyle("Sheet1", date, date, styleId)
+		file.SetCellValue("Sheet1", entry, shift)
+	}
+
+	buffer, err := file.WriteToBuffer()
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return buffer
+}
+
+func setDateValue(file *excelize.File, cell string, date time.Time) {
+	file.SetCellValue("Sheet1", cell, date)
+}
+
+func TestReadXlsx(t *testing.T) {
+	buffer := xlsxRoster(t, &excelize.Style{NumFmt: 14}, setDateValue)
+
+	// the extension is wrong on purpose, the format is recognized from the contents
+	book, err := excelreader.ReadWorkbook(buffer, "rooster.csv")
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	entries, err := excelreader.FindEntries(book, "Firstname")
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	checkEntries(t, entries)
+}
+
+func TestReadXlsxDateFormats(t *testing.T) {
+	customFormat := func(format string) *excelize.Style {
+		return &excelize.Style{CustomNumFmt: &format}
+	}
+
+	styles := map[string]*excelize.Style{
+		"d-mmm":              {NumFmt: 16},
+		"ddd d-m":            customFormat("ddd d-m"),
+		"dutch month":        customFormat("[$-413]d mmmm"),
+		"date and time":      customFormat(`yyyy-mm-dd\ hh:mm;@`),
+		"literal text first": customFormat(`"dag "d`),
+	}
+
+	for name, style := range styles {
+		t.Run(name, func(t *testing.T) {
+			book, err := excelreader.ReadWorkbook(xlsxRoster(t, style, setDateValue), "rooster.xlsx")
+
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			entries, err := excelreader.FindEntries(book, "Firstname")
+
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			checkEntries(t, entries)
+		})
+	}
+}
+
+func TestReadXlsxNumberFormatsAreNoDates(t *testing.T) {
+	customFormat := `0" dagen"`
+	buffer := xlsxRoster(t, &excelize.Style{CustomNumFmt: &customFormat}, func(file *excelize.File, cell string, date time.Time) {
+		file.SetCellValue("Sheet1", cell, date.Day())
+	})
+
+	book, err := excelreader.ReadWorkbook(buffer, "rooster.xlsx")
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := excelreader.FindEntries(book, "Firstname"); err == nil {
+		t.Error("expected numbers with a custom format not to be taken for dates")
+	}
+}
+
+func TestInferYear(t *testing.T) {
+	// dates typed as text in a d-mmm cell, running from December into January
+	start := time.Date(0, 12, 25, 0, 0, 0, 0, time.UTC)
+
+	buffer := xlsxRoster(t, &excelize.Style{NumFmt: 16}, func(file *excelize.File, cell string, date time.Time) {
+		day := start.AddDate(0, 0, int(date.Sub(rosterStart).Hours()/24))
+		file.SetCellStr("Sheet1", cell, day.Format("02-Jan"))
+	})
+
+	book, err := excelreader.ReadWorkbook(buffer, "rooster.xlsx")
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	entries, err := excelreader.FindEntries(book, "Firstname")
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(entries) != 14 {
+		t.Fatalf("expected 14 entries, got %d", len(entries))
+	}
+
+	first, last := entries[0].Date, entries[len(entries)-1].Date
+
+	if first.Year() != time.Now().Year() || first.Month() != time.December || first.Day() != 25 {
+		t.Errorf("expected the roster to start on the 25th of December this year, got %s", first.Format(time.DateOnly))
+	}
+
+	if last.Year() != first.Year()+1 || last.Month() != time.January || last.Day() != 7 {
+		t.Errorf("expected the roster to end on the 7th of January next year, got %s", last.Format(time.DateOnly))
+	}
+}
+
+func odsRoster(t *testing.T) []byte {
+	content := strings.Builder{}
+	content.WriteString(`<?xml version="1.0" encoding="UTF-8"?>
+<office:document-content xmlns:office="urn:oasis:names:tc:opendocument:xmlns:office:1.0"
+  xmlns:table="urn:oasis:names:tc:opendocument:xmlns:table:1.0"
+  xmlns:text="urn:oasis:names:tc:opendocument:xmlns:text:1.0">
+<office:body><office:spreadsheet>
+<table:table table:name="Blad1"><table:table-column table:number-columns-repeated="15"/>
+<table:table-row table:number-rows-repeated="2"><table:table-cell table:number-columns-repeated="1024"/></table:table-row>
+<table:table-row><table:table-cell office:value-type="string"><text:p>Naam</text:p></table:table-cell>`)
+
+	for i := range strings.Fields(rosterShifts) {
+		date := rosterStart.AddDate(0, 0, i)
+		fmt.Fprintf(&content, `<table:table-cell office:value-type="date" office:date-value="%s"><text:p>%s</text:p></table:table-cell>`,
+			date.Format(time.DateOnly), date.Format("Mon 2 Jan"))
+	}
+
+	content.WriteString(`<table:table-cell table:number-columns-repeated="1009"/></table:table-row>
+<table:table-row><table:table-cell><text:p>Firstname<text:s/>Lastname</text:p></table:table-cell>`)
+
+	for _, shift := range strings.Fields(rosterShifts) {
+		fmt.Fprintf(&content, `<table:table-cell office:value-type="string"><text:p>%s</text:p></table:table-cell>`, shift)
+	}
+
+	content.WriteString(`</table:table-row>
+<table:table-row table:number-rows-repeated="1048572"><table:table-cell table:number-columns-repeated="1024"/></table:table-row>
+</table:table></office:spreadsheet></office:body></office:document-content>`)
+
+	buffer := &bytes.Buffer{}
+	archive := zip.NewWriter(buffer)
+
+	for _, file := range []struct{ name, content string }{
+		{"mimetype", "application/vnd.oasis.opendocument.spreadsheet"},
+		{"content.xml", content.String()},
+	} {
+		w, err := archive.Create(file.name)
+
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		w.Write([]byte(file.content))
+	}
+
+	if err := archive.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	return buffer.Bytes()
+}
+
+func TestReadOds(t *testing.T) {
+	book, err := excelreader.ReadWorkbook(bytes.NewReader(odsRoster(t)), "rooster.ods")
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	rows, err := book.Rows("Blad1")
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(rows) != 4 {
+		t.Errorf("expected trailing empty rows to be dropped, got %d rows", len(rows))
+	}
+
+	entries, err := excelreader.FindEntries(book, "Firstname Lastname")
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	checkEntries(t, entries)
+}
+
+func TestReadXls(t *testing.T) {
+	file, err := os.Open("testdata/rooster.xls")
+
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer file.Close()
+
+	book, err := excelreader.ReadWorkbook(file, "rooster.xls")
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if sheets := book.Sheets(); !reflect.DeepEqual(sheets, []string{"Januari", "Uitleg"}) {
+		t.Errorf("expected the sheets Januari and Uitleg, got %v", sheets)
+	}
+
+	entries, err := excelreader.FindEntries(excelreader.SelectSheets(book, []string{"Januari"}), "Firstname Lastname")
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	checkEntries(t, entries)
+
+	rows, err := book.Rows("Uitleg")
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if expected := [][]string{{"d is een dagdienst"}, {"ja"}, {"1.5"}, {"Zoë"}}; !reflect.DeepEqual(rows, expected) {
+		t.Errorf("expected the text, formula and numbers of the cells, got %q", rows)
+	}
+
+	merged, err := book.MergedCells("Uitleg")
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if expected := []excelreader.CellRange{{FromCol: 0, FromRow: 0, ToCol: 3, ToRow: 0}}; !reflect.DeepEqual(merged, expected) {
+		t.Errorf("expected the first row to be merged, got %v", merged)
+	}
+
+	damaged := append([]byte{0xD0, 0xCF, 0x11, 0xE0, 0xA1, 0xB1, 0x1A, 0xE1}, make([]byte, 512)...)
+
+	if _, err := excelreader.ReadWorkbook(bytes.NewReader(damaged), "rooster.xls"); err == nil {
+		t.Error("expected a damaged .xls file to fail")
+	}
+}
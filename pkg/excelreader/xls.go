@@ -0,0 +1,556 @@
+package excelreader
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"strconv"
+	"time"
+	"unicode/utf16"
+
+	"github.com/richardlehane/mscfb"
+	"github.com/xuri/excelize/v2"
+)
+
+// The records of BIFF8, the format of the Workbook stream in .xls files of Excel 97-2003, that are needed to read the
+// text, numbers and dates of cells.
+const (
+	xlsBOF         = 0x0809
+	xlsEOF         = 0x000A
+	xlsFilePass    = 0x002F
+	xlsDateMode    = 0x0022
+	xlsFormat      = 0x041E
+	xlsXF          = 0x00E0
+	xlsBoundSheet  = 0x0085
+	xlsSST         = 0x00FC
+	xlsContinue    = 0x003C
+	xlsLabelSST    = 0x00FD
+	xlsLabel       = 0x0204
+	xlsNumber      = 0x0203
+	xlsRK          = 0x027E
+	xlsMulRK       = 0x00BD
+	xlsFormula     = 0x0006
+	xlsStringValue = 0x0207
+	xlsMergeCells  = 0x00E5
+)
+
+const (
+	biff8Version   = 0x0600
+	xlsWorksheet   = 0x0000
+	xlsHighByte    = 0x01
+	xlsExtString   = 0x04
+	xlsRichString  = 0x08
+	xlsFormulaText = 0x00
+)
+
+var errXlsDamaged = errors.New("cannot read .xls file: the file is damaged")
+
+type xlsRecord struct {
+	kind uint16
+	data []byte
+}
+
+type xlsSheetInfo struct {
+	name   string
+	offset int
+	kind   byte
+}
+
+// xlsGlobals is what the sheets of an .xls file share: the strings of all text cells, and the cell formats that show
+// dates.
+type xlsGlobals struct {
+	date1904    bool
+	strings     []string
+	dateFormats map[int]bool
+	formats     []int
+	sheets      []xlsSheetInfo
+}
+
+// readXls reads the old binary .xls files of Excel 97-2003. These are compound files, of which the Workbook stream
+// holds the workbook as BIFF8 records. Numbers with a date format are recognized as dates.
+func readXls(contents []byte) (Workbook, error) {
+	stream, err := xlsStream(contents)
+
+	if err != nil {
+		return nil, err
+	}
+
+	globals, err := readXlsGlobals(stream)
+
+	if err != nil {
+		return nil, err
+	}
+
+	book := &memoryWorkbook{}
+
+	for _, info := range globals.sheets {
+		// charts and macro sheets have no cells
+		if info.kind != xlsWorksheet {
+			continue
+		}
+
+		sheet, err := globals.readSheet(stream, info)
+
+		if err != nil {
+			return nil, fmt.Errorf("cannot read sheet %s: %w", info.name, err)
+		}
+
+		book.sheets = append(book.sheets, sheet)
+	}
+
+	return book, nil
+}
+
+func xlsStream(contents []byte) ([]byte, error) {
+	doc, err := mscfb.New(bytes.NewReader(contents))
+
+	if err != nil {
+		return nil, fmt.Errorf("cannot read .xls file: %w", err)
+	}
+
+	for entry, err := doc.Next(); err == nil; entry, err = doc.Next() {
+		switch entry.Name {
+		case "Workbook":
+			return io.ReadAll(entry)
+		case "Book":
+			// Excel 5.0 and 95 stored BIFF5 in a stream named Book
+			return nil, ErrLegacyXls
+		}
+	}
+
+	return nil, errors.New("cannot read .xls file: it contains no workbook")
+}
+
+// xlsRecords reads the records of the substream that starts at offset, up to and including its EOF record.
+func xlsRecords(stream []byte, offset int) ([]xlsRecord, error) {
+	records := []xlsRecord{}
+
+	for offset >= 0 && offset+4 <= len(stream) {
+		kind := binary.LittleEndian.Uint16(stream[offset:])
+		size := int(binary.LittleEndian.Uint16(stream[offset+2:]))
+		offset += 4
+
+		if offset+size > len(stream) {
+			return nil, errXlsDamaged
+		}
+
+		records = append(records, xlsRecord{kind: kind, data: stream[offset : offset+size]})
+		offset += size
+
+		if kind == xlsEOF {
+			break
+		}
+	}
+
+	if len(records) == 0 || records[0].kind != xlsBOF || len(records[0].data) < 4 {
+		return nil, errXlsDamaged
+	}
+
+	if binary.LittleEndian.Uint16(records[0].data) != biff8Version {
+		return nil, ErrLegacyXls
+	}
+
+	return records, nil
+}
+
+func readXlsGlobals(stream []byte) (*xlsGlobals, error) {
+	records, err := xlsRecords(stream, 0)
+
+	if err != nil {
+		return nil, err
+	}
+
+	globals := &xlsGlobals{dateFormats: make(map[int]bool)}
+
+	for i, record := range records {
+		switch record.kind {
+		case xlsFilePass:
+			return nil, errors.New("cannot read .xls file: it is protected with a password, save it without one first")
+		case xlsDateMode:
+			globals.date1904 = len(record.data) >= 2 && binary.LittleEndian.Uint16(record.data) == 1
+		case xlsFormat:
+			if len(record.data) < 2 {
+				return nil, errXlsDamaged
+			}
+
+			code, _, err := xlsString(record.data[2:], 2)
+
+			if err != nil {
+				return nil, err
+			}
+
+			globals.dateFormats[int(binary.LittleEndian.Uint16(record.data))] = isDateFormatCode(code)
+		case xlsXF:
+			if len(record.data) < 4 {
+				return nil, errXlsDamaged
+			}
+
+			globals.formats = append(globals.formats, int(binary.LittleEndian.Uint16(record.data[2:])))
+		case xlsBoundSheet:
+			if len(record.data) < 6 {
+				return nil, errXlsDamaged
+			}
+
+			name, _, err := xlsString(record.data[6:], 1)
+
+			if err != nil {
+				return nil, err
+			}
+
+			globals.sheets = append(globals.sheets, xlsSheetInfo{
+				name:   name,
+				offset: int(binary.LittleEndian.Uint32(record.data)),
+				kind:   record.data[5],
+			})
+		case xlsSST:
+			segments := [][]byte{record.data}
+
+			for _, next := range records[i+1:] {
+				if next.kind != xlsContinue {
+					break
+				}
+
+				segments = append(segments, next.data)
+			}
+
+			globals.strings, err = readSharedStrings(&continuedRecord{segments: segments})
+
+			if err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	return globals, nil
+}
+
+// isDate tells whether the cell format with index xf shows a date.
+func (g *xlsGlobals) isDate(xf int) bool {
+	if xf >= len(g.formats) {
+		return false
+	}
+
+	format := g.formats[xf]
+
+	return isBuiltinDateFormat(format) || g.dateFormats[format]
+}
+
+func (g *xlsGlobals) readSheet(stream []byte, info xlsSheetInfo) (*memorySheet, error) {
+	records, err := xlsRecords(stream, info.offset)
+
+	if err != nil {
+		return nil, err
+	}
+
+	sheet := &memorySheet{name: info.name, dates: make(map[cellPosition]time.Time)}
+
+	// the text of a formula is in the String record that follows it
+	var formula *cellPosition
+
+	for _, record := range records {
+		data := record.data
+
+		switch record.kind {
+		case xlsLabelSST:
+			if len(data) < 10 {
+				return nil, errXlsDamaged
+			}
+
+			if index := int(binary.LittleEndian.Uint32(data[6:])); index < len(g.strings) {
+				sheet.set(xlsCell(data), g.strings[index])
+			}
+		case xlsLabel:
+			if len(data) < 6 {
+				return nil, errXlsDamaged
+			}
+
+			text, _, err := xlsString(data[6:], 2)
+
+			if err != nil {
+				return nil, err
+			}
+
+			sheet.set(xlsCell(data), text)
+		case xlsNumber:
+			if len(data) < 14 {
+				return nil, errXlsDamaged
+			}
+
+			g.setNumber(sheet, xlsCell(data), xlsFormatIndex(data), math.Float64frombits(binary.LittleEndian.Uint64(data[6:])))
+		case xlsRK:
+			if len(data) < 10 {
+				return nil, errXlsDamaged
+			}
+
+			g.setNumber(sheet, xlsCell(data), xlsFormatIndex(data), rkNumber(binary.LittleEndian.Uint32(data[6:])))
+		case xlsMulRK:
+			if len(data) < 6 {
+				return nil, errXlsDamaged
+			}
+
+			cell := xlsCell(data)
+
+			for i := 4; i+6 <= len(data)-2; i += 6 {
+				xf := int(binary.LittleEndian.Uint16(data[i:]))
+				g.setNumber(sheet, cell, xf, rkNumber(binary.LittleEndian.Uint32(data[i+2:])))
+				cell.col += 1
+			}
+		case xlsFormula:
+			if len(data) < 14 {
+				return nil, errXlsDamaged
+			}
+
+			cell := xlsCell(data)
+			formula = nil
+
+			if data[12] != 0xFF || data[13] != 0xFF {
+				g.setNumber(sheet, cell, xlsFormatIndex(data), math.Float64frombits(binary.LittleEndian.Uint64(data[6:])))
+			} else if data[6] == xlsFormulaText {
+				formula = &cell
+			}
+		case xlsStringValue:
+			if formula == nil {
+				continue
+			}
+
+			text, _, err := xlsString(data, 2)
+
+			if err != nil {
+				return nil, err
+			}
+
+			sheet.set(*formula, text)
+			formula = nil
+		case xlsMergeCells:
+			if len(data) < 2 {
+				return nil, errXlsDamaged
+			}
+
+			count := int(binary.LittleEndian.Uint16(data))
+
+			for i := 0; i < count && 2+i*8+8 <= len(data); i++ {
+				ref := data[2+i*8:]
+
+				sheet.merged = append(sheet.merged, CellRange{
+					FromRow: int(binary.LittleEndian.Uint16(ref)), ToRow: int(binary.LittleEndian.Uint16(ref[2:])),
+					FromCol: int(binary.LittleEndian.Uint16(ref[4:])), ToCol: int(binary.LittleEndian.Uint16(ref[6:])),
+				})
+			}
+		}
+	}
+
+	return sheet, nil
+}
+
+// setNumber adds a number to a sheet, as a date when its format shows a date.
+func (g *xlsGlobals) setNumber(sheet *memorySheet, cell cellPosition, xf int, value float64) {
+	if g.isDate(xf) {
+		if t, err := excelize.ExcelDateToTime(value, g.date1904); err == nil {
+			year, month, day := t.Date()
+			date := time.Date(year, month, day, 0, 0, 0, 0, time.UTC)
+
+			sheet.dates[cell] = date
+			sheet.set(cell, date.Format("2006-01-02"))
+
+			return
+		}
+	}
+
+	sheet.set(cell, strconv.FormatFloat(value, 'f', -1, 64))
+}
+
+// xlsCell is the position of the cell of a cell record, which starts with the row and the column.
+func xlsCell(data []byte) cellPosition {
+	return cellPosition{col: int(binary.LittleEndian.Uint16(data[2:])), row: int(binary.LittleEndian.Uint16(data))}
+}
+
+// xlsFormatIndex is the cell format of a cell record, which follows the row and the column.
+func xlsFormatIndex(data []byte) int {
+	return int(binary.LittleEndian.Uint16(data[4:]))
+}
+
+// rkNumber decodes a number that is stored in 4 bytes, as either an integer or the high bits of a float, which may
+// have to be divided by 100.
+func rkNumber(rk uint32) float64 {
+	var value float64
+
+	if rk&0x02 != 0 {
+		value = float64(int32(rk) >> 2)
+	} else {
+		value = math.Float64frombits(uint64(rk&^0x03) << 32)
+	}
+
+	if rk&0x01 != 0 {
+		value /= 100
+	}
+
+	return value
+}
+
+// xlsString reads a string that starts with its length in lengthSize bytes, followed by a byte that tells whether the
+// characters are stored in one or two bytes. It returns the string and the number of bytes it took.
+func xlsString(data []byte, lengthSize int) (string, int, error) {
+	if len(data) < lengthSize+1 {
+		return "", 0, errXlsDamaged
+	}
+
+	length := int(data[0])
+
+	if lengthSize == 2 {
+		length = int(binary.LittleEndian.Uint16(data))
+	}
+
+	r := &continuedRecord{segments: [][]byte{data[lengthSize+1:]}}
+	text, err := r.chars(length, data[lengthSize]&xlsHighByte != 0)
+
+	return text, lengthSize + 1 + r.pos, err
+}
+
+// set puts text in a cell of the sheet. Empty text is left out, so that the rows don't end with empty cells.
+func (s *memorySheet) set(cell cellPosition, text string) {
+	if text == "" {
+		return
+	}
+
+	for len(s.rows) <= cell.row {
+		s.rows = append(s.rows, []string{})
+	}
+
+	for len(s.rows[cell.row]) <= cell.col {
+		s.rows[cell.row] = append(s.rows[cell.row], "")
+	}
+
+	s.rows[cell.row][cell.col] = text
+}
+
+// continuedRecord reads a record that is continued in Continue records, like the shared strings. A string whose
+// characters are split over two records repeats in the next record whether they are stored in one or two bytes.
+type continuedRecord struct {
+	segments [][]byte
+	segment  int
+	pos      int
+}
+
+func (r *continuedRecord) bytes(n int) ([]byte, error) {
+	read := []byte{}
+
+	for len(read) < n {
+		if r.pos == len(r.segments[r.segment]) {
+			if r.segment+1 == len(r.segments) {
+				return nil, errXlsDamaged
+			}
+
+			r.segment, r.pos = r.segment+1, 0
+			continue
+		}
+
+		end := r.pos + n - len(read)
+
+		if end > len(r.segments[r.segment]) {
+			end = len(r.segments[r.segment])
+		}
+
+		read = append(read, r.segments[r.segment][r.pos:end]...)
+		r.pos = end
+	}
+
+	return read, nil
+}
+
+func (r *continuedRecord) chars(count int, highByte bool) (string, error) {
+	units := make([]uint16, 0, count)
+
+	for len(units) < count {
+		segment := r.segments[r.segment]
+
+		if r.pos == len(segment) {
+			if r.segment+1 == len(r.segments) {
+				return "", errXlsDamaged
+			}
+
+			r.segment, r.pos = r.segment+1, 0
+
+			flags, err := r.bytes(1)
+
+			if err != nil {
+				return "", err
+			}
+
+			highByte = flags[0]&xlsHighByte != 0
+			continue
+		}
+
+		if !highByte {
+			units = append(units, uint16(segment[r.pos]))
+			r.pos += 1
+		} else if r.pos+2 <= len(segment) {
+			units = append(units, binary.LittleEndian.Uint16(segment[r.pos:]))
+			r.pos += 2
+		} else {
+			return "", errXlsDamaged
+		}
+	}
+
+	return string(utf16.Decode(units)), nil
+}
+
+// readSharedStrings reads the SST record, with the text of all text cells of the workbook.
+func readSharedStrings(r *continuedRecord) ([]string, error) {
+	header, err := r.bytes(8)
+
+	if err != nil {
+		return nil, err
+	}
+
+	count := int(binary.LittleEndian.Uint32(header[4:]))
+	strings := []string{}
+
+	for i := 0; i < count; i++ {
+		start, err := r.bytes(3)
+
+		if err != nil {
+			return nil, err
+		}
+
+		length, flags := int(binary.LittleEndian.Uint16(start)), start[2]
+		runs, extSize := 0, 0
+
+		if flags&xlsRichString != 0 {
+			size, err := r.bytes(2)
+
+			if err != nil {
+				return nil, err
+			}
+
+			runs = int(binary.LittleEndian.Uint16(size))
+		}
+
+		if flags&xlsExtString != 0 {
+			size, err := r.bytes(4)
+
+			if err != nil {
+				return nil, err
+			}
+
+			extSize = int(binary.LittleEndian.Uint32(size))
+		}
+
+		text, err := r.chars(length, flags&xlsHighByte != 0)
+
+		if err != nil {
+			return nil, err
+		}
+
+		// the formatting runs and the phonetic text are not needed
+		if _, err := r.bytes(runs*4 + extSize); err != nil {
+			return nil, err
+		}
+
+		strings = append(strings, text)
+	}
+
+	return strings, nil
+}
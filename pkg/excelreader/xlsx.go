@@ -0,0 +1,191 @@
+package excelreader
+
+import (
+	"bytes"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/xuri/excelize/v2"
+)
+
+// xlsxWorkbook reads Excel files, and uses the number formats of the cells to recognize dates.
+type xlsxWorkbook struct {
+	file     *excelize.File
+	date1904 bool
+}
+
+func readXlsx(contents []byte) (Workbook, error) {
+	file, err := excelize.OpenReader(bytes.NewReader(contents))
+
+	if err != nil {
+		return nil, err
+	}
+
+	props, err := file.GetWorkbookProps()
+
+	if err != nil {
+		return nil, err
+	}
+
+	return &xlsxWorkbook{file: file, date1904: props.Date1904 != nil && *props.Date1904}, nil
+}
+
+func (w *xlsxWorkbook) Sheets() []string {
+	return w.file.GetSheetList()
+}
+
+func (w *xlsxWorkbook) Rows(sheet string) ([][]string, error) {
+	return w.file.GetRows(sheet)
+}
+
+func (w *xlsxWorkbook) MergedCells(sheet string) ([]CellRange, error) {
+	cells, err := w.file.GetMergeCells(sheet)
+
+	if err != nil {
+		return nil, err
+	}
+
+	ranges := make([]CellRange, 0, len(cells))
+
+	for _, cell := range cells {
+		fromCol, fromRow, err := excelize.CellNameToCoordinates(cell.GetStartAxis())
+
+		if err != nil {
+			return nil, err
+		}
+
+		toCol, toRow, err := excelize.CellNameToCoordinates(cell.GetEndAxis())
+
+		if err != nil {
+			return nil, err
+		}
+
+		ranges = append(ranges, CellRange{FromCol: fromCol - 1, FromRow: fromRow - 1, ToCol: toCol - 1, ToRow: toRow - 1})
+	}
+
+	return ranges, nil
+}
+
+// Date decodes cells that have a date format. Excel stores dates as the number of days since 1900 (or 1904), so the
+// date is read from that number, whatever the format displays. Text that was typed into a date formatted cell is
+// parsed with the built-in format instead.
+func (w *xlsxWorkbook) Date(sheet string, col, row int, content string) (time.Time, bool) {
+	cellname, err := excelize.CoordinatesToCellName(col+1, row+1, false)
+
+	if err != nil {
+		return time.Time{}, false
+	}
+
+	format, ok := w.dateFormat(sheet, cellname)
+
+	if !ok {
+		return time.Time{}, false
+	}
+
+	raw, err := w.file.GetCellValue(sheet, cellname, excelize.Options{RawCellValue: true})
+
+	if err != nil {
+		return time.Time{}, false
+	}
+
+	if serial, err := strconv.ParseFloat(raw, 64); err == nil {
+		t, err := excelize.ExcelDateToTime(serial, w.date1904)
+
+		if err != nil {
+			return time.Time{}, false
+		}
+
+		year, month, day := t.Date()
+
+		return time.Date(year, month, day, 0, 0, 0, 0, time.UTC), true
+	}
+
+	if layout, ok := builtinDateLayouts[format]; ok {
+		t, err := time.Parse(layout, content)
+
+		return t, err == nil
+	}
+
+	return time.Time{}, false
+}
+
+// builtinDateLayouts are the built-in Excel date formats, as Go layouts. Formats without a year give dates in year 0.
+var builtinDateLayouts = map[int]string{
+	14: "01-02-06",     // mm-dd-yy
+	15: "02-Jan-06",    // d-mmm-yy
+	16: "02-Jan",       // d-mmm
+	17: "Jan-06",       // mmm-yy
+	22: "1/2/06 15:04", // m/d/yy h:mm
+}
+
+// isBuiltinDateFormat tells whether a built-in number format shows a date. Formats 27 to 36 and 50 to 58 are dates in
+// East Asian versions of Excel.
+func isBuiltinDateFormat(id int) bool {
+	return (id >= 14 && id <= 17) || id == 22 || (id >= 27 && id <= 36) || (id >= 50 && id <= 58)
+}
+
+// dateFormat returns the number format of a cell, when that format shows a date.
+func (w *xlsxWorkbook) dateFormat(sheet, cellname string) (int, bool) {
+	style, err := w.file.GetCellStyle(sheet, cellname)
+
+	if err != nil {
+		return 0, false
+	}
+
+	stylesheet := w.file.Styles.CellXfs.Xf
+
+	if style >= len(stylesheet) || stylesheet[style].NumFmtID == nil {
+		return 0, false
+	}
+
+	id := *stylesheet[style].NumFmtID
+
+	if isBuiltinDateFormat(id) {
+		return id, true
+	}
+
+	if w.file.Styles.NumFmts == nil {
+		return 0, false
+	}
+
+	for _, numFmt := range w.file.Styles.NumFmts.NumFmt {
+		if numFmt.NumFmtID == id {
+			return id, isDateFormatCode(numFmt.FormatCode)
+		}
+	}
+
+	return 0, false
+}
+
+// isDateFormatCode tells whether a custom number format, like "ddd d-m" or "[$-413]d mmmm yyyy", shows a date. Literal
+// text, escaped characters and sections in brackets (locales, colors and elapsed time) are ignored, a date format is
+// then any format that contains a day or a year.
+func isDateFormatCode(code string) bool {
+	// only the first section applies to positive numbers, which dates are
+	section := strings.Builder{}
+	quoted, bracketed, escaped := false, false, false
+
+	for _, c := range code {
+		switch {
+		case escaped:
+			escaped = false
+		case quoted:
+			quoted = c != '"'
+		case bracketed:
+			bracketed = c != ']'
+		case c == '\\' || c == '_' || c == '*':
+			escaped = true
+		case c == '"':
+			quoted = true
+		case c == '[':
+			bracketed = true
+		case c == ';':
+			return strings.ContainsAny(strings.ToLower(section.String()), "dy")
+		default:
+			section.WriteRune(c)
+		}
+	}
+
+	return strings.ContainsAny(strings.ToLower(section.String()), "dy")
+}
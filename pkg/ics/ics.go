@@ -0,0 +1,155 @@
+package ics
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+	"time"
+
+	// make sure the time zone can be loaded on systems without a time zone database, like windows
+	_ "time/tzdata"
+)
+
+const TimeZone = "Europe/Amsterdam"
+
+const prodId = "-//rooster-importer//NL"
+
+// vtimezone describes the daylight saving rules of Europe/Amsterdam, as required by RFC 5545 for every TZID that is
+// referenced in the calendar.
+var vtimezone = []string{
+	"BEGIN:VTIMEZONE",
+	"TZID:" + TimeZone,
+	"BEGIN:DAYLIGHT",
+	"TZOFFSETFROM:+0100",
+	"TZOFFSETTO:+0200",
+	"TZNAME:CEST",
+	"DTSTART:19700329T020000",
+	"RRULE:FREQ=YEARLY;BYMONTH=3;BYDAY=-1SU",
+	"END:DAYLIGHT",
+	"BEGIN:STANDARD",
+	"TZOFFSETFROM:+0200",
+	"TZOFFSETTO:+0100",
+	"TZNAME:CET",
+	"DTSTART:19701025T030000",
+	"RRULE:FREQ=YEARLY;BYMONTH=10;BYDAY=-1SU",
+	"END:STANDARD",
+	"END:VTIMEZONE",
+}
+
+type Event struct {
+	UID    string
+	Title  string
+	Start  time.Time
+	End    time.Time
+	AllDay bool
+
+	// Properties are written as non-standard X- properties, so that they are kept by calendar servers. Keys are
+	// case insensitive, and are read back in lower case.
+	Properties map[string]string
+}
+
+const (
+	dateFormat     = "20060102"
+	dateTimeFormat = "20060102T150405"
+)
+
+// Write writes the events as an iCalendar (RFC 5545) file.
+func Write(w io.Writer, events []Event) error {
+	location, err := time.LoadLocation(TimeZone)
+
+	if err != nil {
+		return fmt.Errorf("cannot load time zone %s: %w", TimeZone, err)
+	}
+
+	out := bufio.NewWriter(w)
+	stamp := time.Now().UTC().Format(dateTimeFormat) + "Z"
+
+	lines := []string{
+		"BEGIN:VCALENDAR",
+		"VERSION:2.0",
+		"PRODID:" + prodId,
+		"CALSCALE:GREGORIAN",
+		"METHOD:PUBLISH",
+	}
+	lines = append(lines, vtimezone...)
+
+	for _, event := range events {
+		lines = append(lines, eventLines(event, location, stamp)...)
+	}
+
+	lines = append(lines, "END:VCALENDAR")
+
+	for _, line := range lines {
+		if _, err := out.WriteString(fold(line)); err != nil {
+			return err
+		}
+	}
+
+	return out.Flush()
+}
+
+func eventLines(event Event, location *time.Location, stamp string) []string {
+	lines := []string{
+		"BEGIN:VEVENT",
+		"UID:" + escape(event.UID),
+		"DTSTAMP:" + stamp,
+		"SUMMARY:" + escape(event.Title),
+	}
+
+	if event.AllDay {
+		lines = append(lines,
+			"DTSTART;VALUE=DATE:"+event.Start.Format(dateFormat),
+			"DTEND;VALUE=DATE:"+event.End.Format(dateFormat),
+			"TRANSP:TRANSPARENT",
+		)
+	} else {
+		lines = append(lines,
+			"DTSTART;TZID="+TimeZone+":"+event.Start.In(location).Format(dateTimeFormat),
+			"DTEND;TZID="+TimeZone+":"+event.End.In(location).Format(dateTimeFormat),
+		)
+	}
+
+	keys := make([]string, 0, len(event.Properties))
+
+	for key := range event.Properties {
+		keys = append(keys, key)
+	}
+
+	sort.Strings(keys)
+
+	for _, key := range keys {
+		lines = append(lines, "X-"+strings.ToUpper(key)+":"+escape(event.Properties[key]))
+	}
+
+	return append(lines, "END:VEVENT")
+}
+
+var escaper = strings.NewReplacer(`\`, `\\`, ";", `\;`, ",", `\,`, "\n", `\n`)
+
+func escape(text string) string {
+	return escaper.Replace(text)
+}
+
+// fold splits content lines that are longer than 75 octets, and terminates the line with CRLF.
+func fold(line string) string {
+	folded := strings.Builder{}
+	length := 0
+
+	for _, r := range line {
+		size := len(string(r))
+
+		if length+size > 75 {
+			folded.WriteString("\r\n ")
+			length = 1
+		}
+
+		folded.WriteRune(r)
+		length += size
+	}
+
+	folded.WriteString("\r\n")
+
+	return folded.String()
+}
@@ -0,0 +1,156 @@
+package ics_test
+
+import (
+	"bytes"
+	"rooster-importer/pkg/ics"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestWrite(t *testing.T) {
+	amsterdam, err := time.LoadLocation(ics.TimeZone)
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	events := []ics.Event{
+		{
+			UID:   "20240105-dag@rooster-importer",
+			Title: "Dag",
+			// given in UTC, should be written in Amsterdam time
+			Start: time.Date(2024, 1, 5, 6, 45, 0, 0, time.UTC),
+			End:   time.Date(2024, 1, 5, 16, 15, 0, 0, amsterdam),
+		},
+		{
+			UID:    "20240106-vrij@rooster-importer",
+			Title:  "Vrij, echt",
+			Start:  time.Date(2024, 1, 6, 0, 0, 0, 0, time.UTC),
+			End:    time.Date(2024, 1, 7, 0, 0, 0, 0, time.UTC),
+			AllDay: true,
+		},
+	}
+
+	buf := &bytes.Buffer{}
+
+	if err := ics.Write(buf, events); err != nil {
+		t.Fatal(err)
+	}
+
+	out := buf.String()
+
+	expected := []string{
+		"BEGIN:VCALENDAR\r\n",
+		"TZID:Europe/Amsterdam\r\n",
+		"DTSTART;TZID=Europe/Amsterdam:20240105T074500\r\n",
+		"DTEND;TZID=Europe/Amsterdam:20240105T161500\r\n",
+		"DTSTART;VALUE=DATE:20240106\r\n",
+		"DTEND;VALUE=DATE:20240107\r\n",
+		"SUMMARY:Vrij\\, echt\r\n",
+		"UID:20240106-vrij@rooster-importer\r\n",
+		"END:VCALENDAR\r\n",
+	}
+
+	for _, line := range expected {
+		if !strings.Contains(out, line) {
+			t.Errorf("expected output to contain %q, got:\n%s", line, out)
+		}
+	}
+}
+
+func TestFolding(t *testing.T) {
+	buf := &bytes.Buffer{}
+	title := strings.Repeat("Nachtdienst ", 20)
+
+	err := ics.Write(buf, []ics.Event{{UID: "long", Title: title, Start: time.Now(), End: time.Now()}})
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, line := range strings.Split(buf.String(), "\r\n") {
+		if len(line) > 75 {
+			t.Errorf("line longer than 75 octets: %q", line)
+		}
+	}
+
+	unfolded := strings.ReplaceAll(buf.String(), "\r\n ", "")
+
+	if !strings.Contains(unfolded, "SUMMARY:"+title) {
+		t.Error("folded summary does not unfold to the original title")
+	}
+}
+
+func TestParseRoundTrip(t *testing.T) {
+	amsterdam, _ := time.LoadLocation(ics.TimeZone)
+
+	events := []ics.Event{
+		{UID: "a", Title: "Nacht", Start: time.Date(2024, 3, 30, 23, 0, 0, 0, amsterdam), End: time.Date(2024, 3, 31, 8, 30, 0, 0, amsterdam), Properties: map[string]string{"rooster-importer": "1"}},
+		{UID: "b", Title: "Vakantie; weg", Start: time.Date(2024, 4, 1, 0, 0, 0, 0, time.UTC), End: time.Date(2024, 4, 2, 0, 0, 0, 0, time.UTC), AllDay: true},
+	}
+
+	buf := &bytes.Buffer{}
+
+	if err := ics.Write(buf, events); err != nil {
+		t.Fatal(err)
+	}
+
+	parsed, err := ics.Parse(buf)
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(parsed) != len(events) {
+		t.Fatalf("expected %d events, got %d", len(events), len(parsed))
+	}
+
+	for i, event := range events {
+		p := parsed[i]
+
+		if p.UID != event.UID || p.Title != event.Title || p.AllDay != event.AllDay || !p.Start.Equal(event.Start) || !p.End.Equal(event.End) || len(p.Properties) != len(event.Properties) {
+			t.Errorf("event %d changed in round trip: %+v became %+v", i, event, p)
+		}
+	}
+}
+
+func TestParseForeignEvents(t *testing.T) {
+	contents := strings.Join([]string{
+		"BEGIN:VCALENDAR",
+		"BEGIN:VEVENT",
+		"UID:x@example.com",
+		"SUMMARY:Verjaardag",
+		"DTSTART;VALUE=DATE:20240105",
+		"BEGIN:VALARM",
+		"SUMMARY:Herinnering",
+		"END:VALARM",
+		"END:VEVENT",
+		"BEGIN:VEVENT",
+		"UID:y@example.com",
+		"SUMMARY:Tandarts met een hele lange omschrijving die over meerdere regels wordt ge",
+		" vouwen",
+		"DTSTART:20240105T090000Z",
+		"DTEND:20240105T093000Z",
+		"END:VEVENT",
+		"END:VCALENDAR",
+	}, "\r\n")
+
+	events, err := ics.Parse(strings.NewReader(contents))
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(events) != 2 {
+		t.Fatalf("expected 2 events, got %d", len(events))
+	}
+
+	if events[0].Title != "Verjaardag" || !events[0].AllDay || events[0].End.Day() != 6 {
+		t.Errorf("unexpected all day event %+v", events[0])
+	}
+
+	if !strings.HasSuffix(events[1].Title, "gevouwen") || events[1].End.Sub(events[1].Start) != 30*time.Minute {
+		t.Errorf("unexpected event %+v", events[1])
+	}
+}
@@ -0,0 +1,179 @@
+package ics
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+)
+
+type property struct {
+	name   string
+	params map[string]string
+	value  string
+}
+
+// Parse reads the events of an iCalendar file. Components other than VEVENT are ignored.
+func Parse(r io.Reader) ([]Event, error) {
+	lines, err := unfold(r)
+
+	if err != nil {
+		return nil, err
+	}
+
+	events := []Event{}
+	var current *Event
+	depth := 0
+
+	for _, line := range lines {
+		prop, err := parseProperty(line)
+
+		if err != nil {
+			return nil, err
+		}
+
+		switch {
+		case prop.name == "BEGIN" && prop.value == "VEVENT":
+			current = &Event{}
+			depth = 0
+		case current == nil:
+			continue
+		case prop.name == "BEGIN":
+			// nested components like VALARM have properties that don't belong to the event
+			depth += 1
+		case prop.name == "END" && depth > 0:
+			depth -= 1
+		case prop.name == "END" && prop.value == "VEVENT":
+			if current.End.IsZero() {
+				current.End = current.Start
+
+				if current.AllDay {
+					current.End = current.Start.AddDate(0, 0, 1)
+				}
+			}
+
+			events = append(events, *current)
+			current = nil
+		case depth > 0:
+			continue
+		default:
+			if err := current.setProperty(prop); err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	return events, nil
+}
+
+func unfold(r io.Reader) ([]string, error) {
+	scanner := bufio.NewScanner(r)
+	lines := []string{}
+
+	for scanner.Scan() {
+		line := strings.TrimRight(scanner.Text(), "\r")
+
+		if (strings.HasPrefix(line, " ") || strings.HasPrefix(line, "\t")) && len(lines) > 0 {
+			lines[len(lines)-1] += line[1:]
+			continue
+		}
+
+		if line != "" {
+			lines = append(lines, line)
+		}
+	}
+
+	return lines, scanner.Err()
+}
+
+func parseProperty(line string) (property, error) {
+	prop := property{params: make(map[string]string)}
+
+	// the value starts after the first colon that is not inside a quoted parameter value
+	quoted := false
+	split := -1
+
+	for i, r := range line {
+		if r == '"' {
+			quoted = !quoted
+		} else if r == ':' && !quoted {
+			split = i
+			break
+		}
+	}
+
+	if split < 0 {
+		return prop, fmt.Errorf("malformed line in calendar: %q", line)
+	}
+
+	prop.value = line[split+1:]
+	parts := strings.Split(line[:split], ";")
+	prop.name = strings.ToUpper(parts[0])
+
+	for _, param := range parts[1:] {
+		key, value, _ := strings.Cut(param, "=")
+		prop.params[strings.ToUpper(key)] = strings.Trim(value, `"`)
+	}
+
+	return prop, nil
+}
+
+func (e *Event) setProperty(prop property) error {
+	var err error
+
+	switch prop.name {
+	case "UID":
+		e.UID = unescape(prop.value)
+	case "SUMMARY":
+		e.Title = unescape(prop.value)
+	case "DTSTART":
+		e.Start, e.AllDay, err = parseTime(prop)
+	case "DTEND":
+		e.End, _, err = parseTime(prop)
+	default:
+		if strings.HasPrefix(prop.name, "X-") {
+			if e.Properties == nil {
+				e.Properties = make(map[string]string)
+			}
+
+			e.Properties[strings.ToLower(prop.name[2:])] = unescape(prop.value)
+		}
+	}
+
+	if err != nil {
+		return fmt.Errorf("cannot parse %s of event %s: %w", prop.name, e.Title, err)
+	}
+
+	return nil
+}
+
+func parseTime(prop property) (time.Time, bool, error) {
+	if prop.params["VALUE"] == "DATE" || len(prop.value) == len(dateFormat) {
+		t, err := time.Parse(dateFormat, prop.value)
+		return t, true, err
+	}
+
+	if strings.HasSuffix(prop.value, "Z") {
+		t, err := time.Parse(dateTimeFormat+"Z", prop.value)
+		return t, false, err
+	}
+
+	location := time.Local
+
+	if tzid, ok := prop.params["TZID"]; ok {
+		if loc, err := time.LoadLocation(tzid); err == nil {
+			location = loc
+		}
+	}
+
+	t, err := time.ParseInLocation(dateTimeFormat, prop.value, location)
+
+	return t, false, err
+}
+
+var unescaper = strings.NewReplacer(`\\`, `\`, `\;`, ";", `\,`, ",", `\n`, "\n", `\N`, "\n")
+
+func unescape(text string) string {
+	return unescaper.Replace(text)
+}
@@ -1,8 +1,12 @@
 package ui
 
 import (
+	"context"
+	"fmt"
 	"io"
 	"rooster-importer/pkg/domain"
+	"strings"
+	"time"
 
 	"fyne.io/fyne/v2"
 	"fyne.io/fyne/v2/app"
@@ -14,19 +18,39 @@ import (
 )
 
 type AppUI struct {
-	mainWindow  fyne.Window
-	uploadLabel *widget.Label
-	nameEntry   *widget.Entry
-	calSelect   *widget.Select
-	preview     *widget.TextGrid
-
-	loginButton  *widget.Button
-	logoutButton *widget.Button
+	mainWindow   fyne.Window
+	uploadLabel  *widget.Label
+	nameSearch   *widget.Entry
+	nameSelect   *widget.Select
+	layoutSelect *widget.Select
+	sheetCheck   *widget.CheckGroup
+	fromEntry    *widget.Entry
+	toEntry      *widget.Entry
+	calSelect    *widget.Select
+	syncCheck    *widget.Check
+	preview      *widget.TextGrid
+	icsButton    *widget.Button
+	teamButton   *widget.Button
+
+	providerSelect *widget.Select
+	loginButton    *widget.Button
+	logoutButton   *widget.Button
 
 	createEventsButton *widget.Button
-
-	events   chan domain.Action
-	progress *widget.ProgressBar
+	undoButton         *widget.Button
+
+	events       chan domain.Action
+	ctx          context.Context
+	stop         context.CancelFunc
+	progress     *widget.ProgressBar
+	cancelButton *widget.Button
+	cancelImport context.CancelFunc
+
+	shifts           []domain.ShiftDefinition
+	names            []string
+	selectedName     string
+	calendarProvider string
+	lastImport       string
 }
 
 type XlsxHandler interface {
@@ -35,22 +59,34 @@ type XlsxHandler interface {
 
 const NO_FILE_SELECTED = "(geen bestand geselecteerd)"
 
+const (
+	GOOGLE_PROVIDER = "Google Calendar"
+	CALDAV_PROVIDER = "CalDAV"
+)
+
 func CreateAppUI() *AppUI {
 	ui := &AppUI{}
 	ui.events = make(chan domain.Action, 4)
 
+	// the calls to the calendar stop when the application is closed
+	ui.ctx, ui.stop = context.WithCancel(context.Background())
+
 	a := app.New()
 	ui.mainWindow = a.NewWindow("Fix je rooster naar Google Calendar")
+	ui.mainWindow.SetOnClosed(ui.stop)
 
-	explainerLabel := widget.NewLabel("Vul je naam (1e kolom van je Excel bestand) in, upload je rooster.xlsx hier, en dit ding vult je Google Calendar in")
+	explainerLabel := widget.NewLabel("Selecteer je rooster.xlsx hier, kies je naam, en dit ding vult je Google Calendar in")
 
 	uploadBox := ui.createUploadBox()
 	googleCalendarBox := ui.createGoogleCalendarBox()
+	mappingBox := ui.createMappingBox()
 
 	ui.mainWindow.SetContent(container.NewVBox(
 		explainerLabel,
 		uploadBox,
 		widget.NewSeparator(),
+		mappingBox,
+		widget.NewSeparator(),
 		googleCalendarBox,
 	))
 
@@ -60,26 +96,146 @@ func CreateAppUI() *AppUI {
 func (u *AppUI) createUploadBox() *fyne.Container {
 	button := widget.NewButton("Selecteer rooster", u.clickUploadButton)
 	u.uploadLabel = widget.NewLabel(NO_FILE_SELECTED)
-	u.nameEntry = widget.NewEntry()
 	uploader := container.NewHBox(button, u.uploadLabel)
 
+	// the names are known once a roster is selected, typing in the search entry only narrows down the names to pick from
+	u.nameSearch = widget.NewEntry()
+	u.nameSearch.SetPlaceHolder("selecteer eerst een rooster")
+	u.nameSearch.OnChanged = u.filterNames
+	u.nameSearch.OnSubmitted = func(s string) {
+		// names that aren't in the list can still be used, they are matched to the closest name in the roster
+		if s != "" && s != u.selectedName {
+			u.events <- domain.SelectNameAction(u.ctx, s)
+		}
+	}
+	u.nameSearch.Disable()
+
+	u.nameSelect = widget.NewSelect(nil, func(s string) {
+		if s != "" && s != u.selectedName {
+			u.events <- domain.SelectNameAction(u.ctx, s)
+		}
+	})
+	u.nameSelect.PlaceHolder = "kies je naam"
+	u.nameSelect.Disable()
+
 	namelabel := widget.NewLabel("Naam")
-	nameform := container.New(layout.NewFormLayout(), namelabel, u.nameEntry)
+
+	u.layoutSelect = widget.NewSelect([]string{domain.AutoLayout}, func(s string) {
+		u.events <- domain.SelectLayoutAction(u.ctx, s)
+	})
+	u.layoutSelect.Selected = domain.AutoLayout
+
+	layoutlabel := widget.NewLabel("Indeling")
+
+	// only the checked sheets are read, so that months that were imported before can be left out
+	u.sheetCheck = widget.NewCheckGroup(nil, func(sheets []string) {
+		u.events <- domain.SelectSheetsAction(u.ctx, sheets)
+	})
+	u.sheetCheck.Horizontal = true
+
+	sheetlabel := widget.NewLabel("Tabbladen")
+
+	u.fromEntry = widget.NewEntry()
+	u.fromEntry.SetPlaceHolder("dd-mm-jjjj, leeg voor het begin van het rooster")
+	u.fromEntry.OnSubmitted = func(string) { u.submitDateRange() }
+
+	todayButton := widget.NewButton("Vanaf vandaag", func() {
+		u.fromEntry.SetText(time.Now().Format(dateEntryLayout))
+		u.submitDateRange()
+	})
+
+	u.toEntry = widget.NewEntry()
+	u.toEntry.SetPlaceHolder("dd-mm-jjjj, leeg voor het eind van het rooster")
+	u.toEntry.OnSubmitted = func(string) { u.submitDateRange() }
+
+	fromlabel := widget.NewLabel("Van")
+	tolabel := widget.NewLabel("Tot en met")
+
+	nameform := container.New(layout.NewFormLayout(),
+		namelabel, container.NewGridWithColumns(2, u.nameSearch, u.nameSelect),
+		layoutlabel, u.layoutSelect,
+		sheetlabel, u.sheetCheck,
+		fromlabel, container.NewBorder(nil, nil, nil, todayButton, u.fromEntry),
+		tolabel, u.toEntry,
+	)
 
 	u.preview = widget.NewTextGrid()
 	winwidth, _ := u.mainWindow.Canvas().Size().Components()
 	previewScroller := container.NewVScroll(u.preview)
 	previewScroller.SetMinSize(fyne.NewSize(winwidth, 200))
 
-	uploadBox := container.NewVBox(nameform, uploader, previewScroller)
+	u.icsButton = widget.NewButton("Opslaan als .ics", u.clickIcsButton)
+	u.icsButton.Disable()
+
+	u.teamButton = widget.NewButton("Opslaan als .ics voor iedereen", u.clickTeamButton)
+	u.teamButton.Disable()
+
+	uploadBox := container.NewVBox(uploader, nameform, previewScroller, container.NewGridWithColumns(2, u.icsButton, u.teamButton))
 
 	return container.NewPadded(uploadBox)
 }
 
+const dateEntryLayout = "2-1-2006"
+
+// submitDateRange only converts the shifts between the dates in the date entries. An empty entry leaves the range
+// open at that end.
+func (u *AppUI) submitDateRange() {
+	dates := []time.Time{}
+
+	for _, entry := range []*widget.Entry{u.fromEntry, u.toEntry} {
+		if strings.TrimSpace(entry.Text) == "" {
+			dates = append(dates, time.Time{})
+			continue
+		}
+
+		date, err := time.ParseInLocation(dateEntryLayout, strings.TrimSpace(entry.Text), time.Local)
+
+		if err != nil {
+			dialog.ShowError(fmt.Errorf("%s is geen datum, gebruik dd-mm-jjjj", entry.Text), u.mainWindow)
+			return
+		}
+
+		dates = append(dates, date)
+	}
+
+	u.events <- domain.SetDateRangeAction(u.ctx, dates[0], dates[1])
+}
+
+// filterNames offers the names that contain the search text in the name select.
+func (u *AppUI) filterNames(search string) {
+	search = strings.ToLower(strings.TrimSpace(search))
+	options := []string{}
+
+	for _, name := range u.names {
+		if strings.Contains(strings.ToLower(name), search) {
+			options = append(options, name)
+		}
+	}
+
+	u.nameSelect.SetOptions(options)
+}
+
 func (u *AppUI) createGoogleCalendarBox() *fyne.Container {
 	label := widget.NewLabel("Google Calendar stuff")
+
+	u.providerSelect = widget.NewSelect([]string{GOOGLE_PROVIDER, CALDAV_PROVIDER}, nil)
+	u.providerSelect.SetSelected(GOOGLE_PROVIDER)
+	u.providerSelect.OnChanged = func(s string) {
+		if s == CALDAV_PROVIDER {
+			// use the server of a previous login, if there is one
+			u.events <- domain.UseCalDAVAction(u.ctx, "", "", "")
+		} else {
+			u.events <- domain.UseGoogleCalendarAction(u.ctx)
+		}
+	}
+
 	u.loginButton = widget.NewButton("Log in", func() {
-		u.events <- domain.ClickedCalendarLoginAction()
+		if u.calendarProvider == CALDAV_PROVIDER {
+			u.showCalDAVLogin()
+			return
+		}
+
+		u.events <- domain.ClickedCalendarLoginAction(u.ctx)
 	})
 	u.loginButton.Disable()
 
@@ -91,28 +247,74 @@ func (u *AppUI) createGoogleCalendarBox() *fyne.Container {
 	buttonBox := container.NewHBox(u.loginButton, u.logoutButton)
 
 	u.calSelect = widget.NewSelect([]string{}, func(s string) {
-		u.events <- domain.SelectCalendarAction(s)
+		u.events <- domain.SelectCalendarAction(u.ctx, s)
 	})
 
 	u.calSelect.Disable()
 
+	u.syncCheck = widget.NewCheck("Synchroniseer: werk gewijzigde diensten bij en verwijder vervallen diensten", func(b bool) {
+		u.events <- domain.SetSyncModeAction(b)
+	})
+
 	u.createEventsButton = widget.NewButton("Create Events", func() {
 
 		u.createEventsButton.Disable()
 
 		dialog.NewConfirm("Start upload?", "Start uploading? Is the calendar correct?", func(b bool) {
 			if b {
-				u.events <- domain.ImportEntriesToCalendar()
+				ctx, cancel := context.WithCancel(u.ctx)
+				u.cancelImport = cancel
+				u.events <- domain.ImportEntriesToCalendar(ctx)
 			}
 		}, u.mainWindow).Show()
 	})
 
 	u.createEventsButton.Disable()
 
+	u.undoButton = widget.NewButton("Maak laatste import ongedaan", func() {
+		dialog.NewConfirm("Undo last import?", fmt.Sprintf("Remove the %s?", u.lastImport), func(b bool) {
+			if b {
+				ctx, cancel := context.WithCancel(u.ctx)
+				u.cancelImport = cancel
+				u.events <- domain.UndoLastImportAction(ctx)
+			}
+		}, u.mainWindow).Show()
+	})
+
+	u.undoButton.Disable()
+
 	u.progress = widget.NewProgressBar()
 	u.progress.Hide()
 
-	return container.NewPadded(container.NewVBox(label, buttonBox, u.calSelect, u.createEventsButton, u.progress))
+	u.cancelButton = widget.NewButton("Annuleer", func() {
+		u.cancelButton.Disable()
+		u.cancelImport()
+	})
+	u.cancelButton.Hide()
+
+	progressBox := container.NewBorder(nil, nil, nil, u.cancelButton, u.progress)
+
+	return container.NewPadded(container.NewVBox(container.NewHBox(label, u.providerSelect), buttonBox, u.calSelect, u.syncCheck, u.createEventsButton, progressBox, u.undoButton))
+}
+
+func (u *AppUI) showCalDAVLogin() {
+	urlEntry := widget.NewEntry()
+	urlEntry.SetPlaceHolder("https://cloud.example.com/remote.php/dav/")
+	usernameEntry := widget.NewEntry()
+	passwordEntry := widget.NewPasswordEntry()
+
+	items := []*widget.FormItem{
+		widget.NewFormItem("Server", urlEntry),
+		widget.NewFormItem("Gebruikersnaam", usernameEntry),
+		widget.NewFormItem("App wachtwoord", passwordEntry),
+	}
+
+	dialog.ShowForm("Log in bij CalDAV", "Log in", "Annuleer", items, func(ok bool) {
+		if ok {
+			u.events <- domain.UseCalDAVAction(u.ctx, urlEntry.Text, usernameEntry.Text, passwordEntry.Text)
+			u.events <- domain.ClickedCalendarLoginAction(u.ctx)
+		}
+	}, u.mainWindow)
 }
 
 func (u *AppUI) Events() <-chan domain.Action {
@@ -122,16 +324,49 @@ func (u *AppUI) Events() <-chan domain.Action {
 func (u *AppUI) clickUploadButton() {
 	fileOpen := dialog.NewFileOpen(func(uc fyne.URIReadCloser, err error) {
 		if uc != nil {
-			u.events <- domain.SelectedXlsxFileAction(uc, uc.URI().Path(), u.nameEntry.Text)
+			u.events <- domain.SelectedXlsxFileAction(u.ctx, uc, uc.URI().Path(), u.selectedName)
 		} else {
 			u.uploadLabel.SetText(NO_FILE_SELECTED)
 		}
 	}, u.mainWindow)
 
-	fileOpen.SetFilter(storage.NewExtensionFileFilter([]string{".xlsx"}))
+	fileOpen.SetFilter(storage.NewExtensionFileFilter([]string{".xlsx", ".ods", ".csv", ".tsv", ".xls"}))
 	fileOpen.Show()
 }
 
+func (u *AppUI) clickIcsButton() {
+	fileSave := dialog.NewFileSave(func(uc fyne.URIWriteCloser, err error) {
+		if err != nil {
+			dialog.ShowError(err, u.mainWindow)
+			return
+		}
+
+		if uc != nil {
+			u.events <- domain.ExportIcsAction(uc, uc.URI().Path())
+		}
+	}, u.mainWindow)
+
+	fileSave.SetFileName("rooster.ics")
+	fileSave.SetFilter(storage.NewExtensionFileFilter([]string{".ics"}))
+	fileSave.Show()
+}
+
+// clickTeamButton asks for a folder, in which the roster of everyone in the selected file is saved.
+func (u *AppUI) clickTeamButton() {
+	folderOpen := dialog.NewFolderOpen(func(uri fyne.ListableURI, err error) {
+		if err != nil {
+			dialog.ShowError(err, u.mainWindow)
+			return
+		}
+
+		if uri != nil {
+			u.events <- domain.ExportTeamIcsAction(uri.Path(), false)
+		}
+	}, u.mainWindow)
+
+	folderOpen.Show()
+}
+
 func (u *AppUI) ShowAndRun() {
 	u.mainWindow.ShowAndRun()
 	close(u.events)
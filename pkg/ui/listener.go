@@ -1,19 +1,37 @@
 package ui
 
 import (
+	"errors"
 	"fmt"
 	"rooster-importer/pkg/domain"
+	"rooster-importer/pkg/excelreader"
 	"strings"
 
+	"fyne.io/fyne/v2"
 	"fyne.io/fyne/v2/dialog"
+	"fyne.io/fyne/v2/widget"
 )
 
 func (ui *AppUI) SubscribeToApp(events <-chan interface{}) {
-	ui.events <- domain.GuiAttachedAction()
+	ui.events <- domain.GuiAttachedAction(ui.ctx)
 
 	for event := range events {
 		switch e := event.(type) {
 		case error:
+			var ambiguous *excelreader.AmbiguousNameError
+
+			if errors.As(e, &ambiguous) {
+				ui.showNameChoice(ambiguous)
+				continue
+			}
+
+			var existing *domain.ExistingFilesError
+
+			if errors.As(e, &existing) {
+				ui.confirmOverwrite(existing)
+				continue
+			}
+
 			dialog.ShowError(e, ui.mainWindow)
 
 		case domain.Information:
@@ -24,6 +42,9 @@ func (ui *AppUI) SubscribeToApp(events <-chan interface{}) {
 			state := domain.UIState(e)
 
 			ui.uploadLabel.SetText(state.SelectedXlsxFile)
+			ui.shifts = state.Shifts
+			ui.calendarProvider = state.CalendarProvider
+			ui.lastImport = state.LastImport
 
 			if state.IsLoggedIn {
 				ui.loginButton.Disable()
@@ -33,84 +54,63 @@ func (ui *AppUI) SubscribeToApp(events <-chan interface{}) {
 				ui.logoutButton.Disable()
 			}
 
-			if len(state.AvailableCalendars) > 0 {
-				ui.calSelect.SetOptions(state.AvailableCalendars)
-				ui.calSelect.Enable()
-			} else {
-				ui.calSelect.Disable()
+			if state.SelectedCalendarName == "" && ui.calSelect.Selected != "" {
+				showSelection(ui.calSelect, ui.calSelect.Options)
 			}
 
-			// Build text block for event summary
-			convertedCount := len(state.ConvertedEvents)
-			warningCount := len(state.WarningEvents)
-			freeDayCount := len(state.FreeDays)
-			skippedCount := len(state.SkippedDays)
-			newEventCount := len(state.EventsNotAlreadyInCalendar)
+			ui.names = state.Names
+			ui.selectedName = state.SelectedName
 
-			previewlines := strings.Builder{}
-
-			if convertedCount+freeDayCount > 0 {
-				previewlines.WriteString(fmt.Sprintf("Schedule conversion summary:\nNew Schedule events: %d", convertedCount))
-
-				if warningCount > 0 {
-					previewlines.WriteString(fmt.Sprintf(" (%d not sure of time)", warningCount))
-				}
+			showSelection(ui.nameSelect, ui.nameSelect.Options, state.SelectedName)
+			ui.filterNames(ui.nameSearch.Text)
 
-				previewlines.WriteString(fmt.Sprintf("\nEvents free: %d\nTotal things processed: %d\n", freeDayCount, convertedCount+freeDayCount))
+			if state.SelectedXlsxFile != "" {
+				ui.nameSearch.SetPlaceHolder("zoek je naam")
+				ui.nameSearch.Enable()
+				ui.nameSelect.Enable()
+			} else {
+				ui.nameSearch.Disable()
+				ui.nameSelect.Disable()
 			}
 
-			// Build a preview of the first and last events to be added to the calendar
-			if convertedCount > 0 {
-				previewlines.WriteString("\nFirst event: ")
-				previewlines.WriteString(state.ConvertedEvents[0].Summary())
-			}
+			showSelection(ui.sheetCheck, state.Sheets, state.SelectedSheets...)
+			showSelection(ui.layoutSelect, state.Layouts, state.SelectedLayout)
 
-			if convertedCount > 1 {
-				previewlines.WriteString("\nLast event: ")
-				previewlines.WriteString(state.ConvertedEvents[convertedCount-1].Summary())
+			if len(state.AvailableCalendars) > 0 {
+				ui.calSelect.SetOptions(state.AvailableCalendars)
+				ui.calSelect.Enable()
+			} else {
+				ui.calSelect.Disable()
 			}
 
-			previewlines.WriteString("\n")
+			ui.preview.SetText(state.Summary())
 
-			previewlines.WriteString(fmt.Sprintf("\nNew events: %d\n", newEventCount))
-
-			if newEventCount > 0 {
-				previewlines.WriteString(fmt.Sprintf("First event: %s\n", state.EventsNotAlreadyInCalendar[0].Summary()))
-
-				if newEventCount > 1 {
-					previewlines.WriteString(fmt.Sprintf("Last event: %s\n", state.EventsNotAlreadyInCalendar[newEventCount-1].Summary()))
-				}
-
-				for _, event := range state.EventsNotAlreadyInCalendar {
-					previewlines.WriteString(fmt.Sprintf("%s\n", event.Summary()))
-				}
+			if len(state.ConvertedEvents) > 0 {
+				ui.icsButton.Enable()
+			} else {
+				ui.icsButton.Disable()
 			}
 
-			if warningCount > 0 {
-				previewlines.WriteString("\nEvents where time is not explicit:\n")
-
-				for _, warning := range state.WarningEvents {
-					previewlines.WriteString(warning.Summary())
-					previewlines.WriteString("\n")
-				}
+			if len(state.Names) > 0 {
+				ui.teamButton.Enable()
+			} else {
+				ui.teamButton.Disable()
 			}
 
-			if freeDayCount > 0 || skippedCount > 0 {
-				previewlines.WriteString(fmt.Sprintf("\nFree dates (%d weekends not included):\n", skippedCount))
-
-				for i, date := range state.FreeDays {
-					previewlines.WriteString(fmt.Sprintf("%s  ", date.Format("02/01")))
-
-					if i%7 == 6 {
-						previewlines.WriteString("\n")
-					}
-				}
+			if state.IsLoggedIn && state.LastImport != "" {
+				ui.undoButton.Enable()
+			} else {
+				ui.undoButton.Disable()
 			}
 
-			ui.preview.SetText(previewlines.String())
+			changeCount := len(state.EventsNotAlreadyInCalendar) + len(state.EventsToUpdate) + len(state.EventsToDelete)
 
-			if state.IsLoggedIn && len(state.EventsNotAlreadyInCalendar) > 0 && state.SelectedCalendarName != "" {
-				ui.createEventsButton.SetText(fmt.Sprintf("Create %d events in %s", len(state.EventsNotAlreadyInCalendar), state.SelectedCalendarName))
+			if state.IsLoggedIn && changeCount > 0 && state.SelectedCalendarName != "" {
+				if state.SyncMode {
+					ui.createEventsButton.SetText(fmt.Sprintf("Create %d, update %d and delete %d events in %s", len(state.EventsNotAlreadyInCalendar), len(state.EventsToUpdate), len(state.EventsToDelete), state.SelectedCalendarName))
+				} else {
+					ui.createEventsButton.SetText(fmt.Sprintf("Create %d events in %s", len(state.EventsNotAlreadyInCalendar), state.SelectedCalendarName))
+				}
 				ui.createEventsButton.Enable()
 			} else {
 				ui.createEventsButton.SetText("Create Events")
@@ -120,13 +120,19 @@ func (ui *AppUI) SubscribeToApp(events <-chan interface{}) {
 		case domain.Progress:
 			if ui.progress.Hidden {
 				ui.progress.Show()
+				ui.cancelButton.Enable()
+				ui.cancelButton.Show()
 			}
 
-			ui.progress.SetValue(float64(e.Done) / float64(e.Total))
+			if e.Total > 0 {
+				ui.progress.SetValue(float64(e.Done) / float64(e.Total))
+			}
 
-			if e.Done == e.Total {
+			if e.Finished {
 				ui.createEventsButton.Enable()
 				ui.progress.Hide()
+				ui.cancelButton.Hide()
+				ui.cancelImport()
 			}
 
 		default:
@@ -134,3 +140,51 @@ func (ui *AppUI) SubscribeToApp(events <-chan interface{}) {
 		}
 	}
 }
+
+// showSelection shows the options and the selection of a select or check group as they are in the domain. They are set
+// directly, because SetSelected and ClearSelected call OnChanged, which would select them in the domain again.
+func showSelection(w fyne.Widget, options []string, selected ...string) {
+	switch w := w.(type) {
+	case *widget.Select:
+		w.Options = options
+		w.Selected = ""
+
+		if len(selected) > 0 {
+			w.Selected = selected[0]
+		}
+	case *widget.CheckGroup:
+		w.Options = options
+		w.Selected = selected
+	}
+
+	w.Refresh()
+}
+
+// confirmOverwrite asks whether the rosters of the team may replace the files that are already in the chosen folder.
+func (ui *AppUI) confirmOverwrite(existing *domain.ExistingFilesError) {
+	message := fmt.Sprintf("%d bestanden bestaan al in %s:\n%s\nOverschrijven?", len(existing.Files), existing.Dir, strings.Join(existing.Files, "\n"))
+
+	dialog.ShowConfirm("Bestanden overschrijven?", message, func(ok bool) {
+		if ok {
+			ui.events <- domain.ExportTeamIcsAction(existing.Dir, true)
+		}
+	}, ui.mainWindow)
+}
+
+// showNameChoice asks the user which of the people in the roster they are, when their name matches several of them.
+func (ui *AppUI) showNameChoice(ambiguous *excelreader.AmbiguousNameError) {
+	choice := widget.NewRadioGroup(ambiguous.Candidates, nil)
+	choice.SetSelected(ambiguous.Candidates[0])
+
+	items := []*widget.FormItem{
+		widget.NewFormItem("Naam", choice),
+	}
+
+	title := fmt.Sprintf("Meerdere mensen heten %s", ambiguous.Name)
+
+	dialog.ShowForm(title, "Kies", "Annuleer", items, func(ok bool) {
+		if ok && choice.Selected != "" {
+			ui.events <- domain.SelectNameAction(ui.ctx, choice.Selected)
+		}
+	}, ui.mainWindow)
+}
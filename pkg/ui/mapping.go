@@ -0,0 +1,285 @@
+package ui
+
+import (
+	"fmt"
+	"rooster-importer/pkg/domain"
+	"strings"
+	"time"
+
+	"fyne.io/fyne/v2"
+	"fyne.io/fyne/v2/container"
+	"fyne.io/fyne/v2/dialog"
+	"fyne.io/fyne/v2/layout"
+	"fyne.io/fyne/v2/widget"
+)
+
+// mappingEditor is a window in which the shift mapping table can be edited. It works on a copy of the shifts, which
+// are sent to the domain when the user saves.
+type mappingEditor struct {
+	window   fyne.Window
+	shifts   []domain.ShiftDefinition
+	selected int
+
+	list         *widget.List
+	codeEntry    *widget.Entry
+	aliasEntry   *widget.Entry
+	titleEntry   *widget.Entry
+	startPicker  *timePicker
+	endPicker    *timePicker
+	allDayCheck  *widget.Check
+	nextDayCheck *widget.Check
+	form         *fyne.Container
+
+	// set while the form is filled from a shift, so that change handlers don't write back half filled values
+	loading bool
+}
+
+// emptyCellAlias is shown in the editor in place of the alias that matches empty cells
+const emptyCellAlias = "(leeg)"
+
+// timePicker lets the user pick a time of day as hours and minutes.
+type timePicker struct {
+	hours   *widget.Select
+	minutes *widget.Select
+	changed func()
+}
+
+func newTimePicker(changed func()) *timePicker {
+	hours := make([]string, 24)
+	for i := range hours {
+		hours[i] = fmt.Sprintf("%02d", i)
+	}
+
+	minutes := make([]string, 12)
+	for i := range minutes {
+		minutes[i] = fmt.Sprintf("%02d", i*5)
+	}
+
+	p := &timePicker{changed: changed}
+	p.hours = widget.NewSelect(hours, func(string) { p.changed() })
+	p.minutes = widget.NewSelect(minutes, func(string) { p.changed() })
+
+	return p
+}
+
+func (p *timePicker) SetTime(clock string) {
+	t, err := time.Parse("15:04", clock)
+
+	if err != nil {
+		p.hours.ClearSelected()
+		p.minutes.ClearSelected()
+		return
+	}
+
+	hours, minutes := t.Format("15"), t.Format("04")
+
+	// times that are not a multiple of 5 minutes can still be shown
+	if !contains(p.minutes.Options, minutes) {
+		p.minutes.Options = append(p.minutes.Options, minutes)
+	}
+
+	p.hours.SetSelected(hours)
+	p.minutes.SetSelected(minutes)
+}
+
+func (p *timePicker) Time() string {
+	if p.hours.Selected == "" || p.minutes.Selected == "" {
+		return ""
+	}
+
+	return fmt.Sprintf("%s:%s", p.hours.Selected, p.minutes.Selected)
+}
+
+func (p *timePicker) SetEnabled(enabled bool) {
+	if enabled {
+		p.hours.Enable()
+		p.minutes.Enable()
+	} else {
+		p.hours.Disable()
+		p.minutes.Disable()
+	}
+}
+
+func (p *timePicker) Widget() fyne.CanvasObject {
+	return container.NewHBox(p.hours, widget.NewLabel(":"), p.minutes)
+}
+
+func contains(options []string, option string) bool {
+	for _, o := range options {
+		if o == option {
+			return true
+		}
+	}
+	return false
+}
+
+func (u *AppUI) createMappingBox() *fyne.Container {
+	label := widget.NewLabel("Diensten")
+	button := widget.NewButton("Diensten bewerken", u.showMappingEditor)
+
+	return container.NewPadded(container.NewHBox(label, button))
+}
+
+func (u *AppUI) showMappingEditor() {
+	e := &mappingEditor{
+		window:   fyne.CurrentApp().NewWindow("Diensten bewerken"),
+		shifts:   make([]domain.ShiftDefinition, len(u.shifts)),
+		selected: -1,
+	}
+
+	copy(e.shifts, u.shifts)
+
+	e.list = widget.NewList(
+		func() int { return len(e.shifts) },
+		func() fyne.CanvasObject { return widget.NewLabel("") },
+		func(id widget.ListItemID, item fyne.CanvasObject) {
+			shift := e.shifts[id]
+			item.(*widget.Label).SetText(fmt.Sprintf("%s: %s", shift.Code, shift.Title))
+		},
+	)
+	e.list.OnSelected = e.selectShift
+
+	e.codeEntry = widget.NewEntry()
+	e.aliasEntry = widget.NewEntry()
+	e.aliasEntry.SetPlaceHolder(fmt.Sprintf("bijv. d (als), dag, %s", emptyCellAlias))
+	e.titleEntry = widget.NewEntry()
+	e.startPicker = newTimePicker(e.formChanged)
+	e.endPicker = newTimePicker(e.formChanged)
+	e.allDayCheck = widget.NewCheck("Hele dag", func(bool) { e.formChanged() })
+	e.nextDayCheck = widget.NewCheck("Eindigt de volgende dag", func(bool) { e.formChanged() })
+
+	e.codeEntry.OnChanged = func(string) { e.formChanged() }
+	e.aliasEntry.OnChanged = func(string) { e.formChanged() }
+	e.titleEntry.OnChanged = func(string) { e.formChanged() }
+
+	e.form = container.New(layout.NewFormLayout(),
+		widget.NewLabel("Code"), e.codeEntry,
+		widget.NewLabel("Andere codes"), e.aliasEntry,
+		widget.NewLabel("Titel"), e.titleEntry,
+		widget.NewLabel("Begin"), e.startPicker.Widget(),
+		widget.NewLabel("Eind"), e.endPicker.Widget(),
+		widget.NewLabel(""), e.allDayCheck,
+		widget.NewLabel(""), e.nextDayCheck,
+	)
+	e.form.Hide()
+
+	addButton := widget.NewButton("Nieuwe dienst", func() {
+		e.shifts = append(e.shifts, domain.ShiftDefinition{
+			Title:      "Nieuwe dienst",
+			Start:      "08:00",
+			End:        "17:00",
+			Conversion: domain.ConversionConverted,
+		})
+		e.list.Refresh()
+		e.list.Select(len(e.shifts) - 1)
+	})
+
+	deleteButton := widget.NewButton("Verwijder dienst", func() {
+		if e.selected < 0 {
+			return
+		}
+
+		e.shifts = append(e.shifts[:e.selected], e.shifts[e.selected+1:]...)
+		e.selected = -1
+		e.list.UnselectAll()
+		e.list.Refresh()
+		e.form.Hide()
+	})
+
+	saveButton := widget.NewButton("Opslaan", func() {
+		if err := domain.ValidateShifts(e.shifts); err != nil {
+			dialog.ShowError(err, e.window)
+			return
+		}
+
+		u.events <- domain.SaveShiftMappingAction(e.shifts)
+		e.window.Close()
+	})
+
+	buttons := container.NewHBox(addButton, deleteButton, layout.NewSpacer(), saveButton)
+	split := container.NewHSplit(e.list, container.NewVScroll(e.form))
+	split.SetOffset(0.3)
+
+	e.window.SetContent(container.NewBorder(nil, buttons, nil, nil, split))
+	e.window.Resize(fyne.NewSize(700, 400))
+	e.window.Show()
+}
+
+func (e *mappingEditor) selectShift(id widget.ListItemID) {
+	e.selected = id
+	shift := e.shifts[id]
+
+	e.loading = true
+	e.codeEntry.SetText(shift.Code)
+	e.aliasEntry.SetText(formatAliases(shift.Aliases))
+	e.titleEntry.SetText(shift.Title)
+	e.startPicker.SetTime(shift.Start)
+	e.endPicker.SetTime(shift.End)
+	e.allDayCheck.SetChecked(shift.AllDay)
+	e.nextDayCheck.SetChecked(shift.NextDayEnd)
+	e.loading = false
+
+	e.updateEnabled()
+	e.form.Show()
+}
+
+func (e *mappingEditor) formChanged() {
+	if e.loading || e.selected < 0 {
+		return
+	}
+
+	shift := &e.shifts[e.selected]
+
+	shift.Code = strings.TrimSpace(e.codeEntry.Text)
+	shift.Title = strings.TrimSpace(e.titleEntry.Text)
+	shift.Start = e.startPicker.Time()
+	shift.End = e.endPicker.Time()
+	shift.AllDay = e.allDayCheck.Checked
+	shift.NextDayEnd = e.nextDayCheck.Checked
+	shift.Aliases = parseAliases(e.aliasEntry.Text)
+
+	e.updateEnabled()
+	e.list.RefreshItem(e.selected)
+}
+
+func formatAliases(aliases []string) string {
+	formatted := make([]string, len(aliases))
+
+	for i, alias := range aliases {
+		if strings.TrimSpace(alias) == "" {
+			alias = emptyCellAlias
+		}
+		formatted[i] = alias
+	}
+
+	return strings.Join(formatted, ", ")
+}
+
+func parseAliases(text string) []string {
+	aliases := []string{}
+
+	for _, alias := range strings.Split(text, ",") {
+		alias = strings.TrimSpace(alias)
+
+		if alias == emptyCellAlias {
+			aliases = append(aliases, "")
+		} else if alias != "" {
+			aliases = append(aliases, alias)
+		}
+	}
+
+	return aliases
+}
+
+func (e *mappingEditor) updateEnabled() {
+	allDay := e.allDayCheck.Checked
+
+	e.startPicker.SetEnabled(!allDay)
+	e.endPicker.SetEnabled(!allDay)
+
+	if allDay {
+		e.nextDayCheck.Disable()
+	} else {
+		e.nextDayCheck.Enable()
+	}
+}